@@ -3,7 +3,6 @@ package ALS
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"math/rand"
 	"sort"
@@ -16,9 +15,10 @@ var (
 	NA = math.NaN()
 )
 
+// for matrix errors that the models' own dimensions rule out. Errors from the caller's input are returned.
 func errcheck(err error) {
 	if err != nil {
-		fmt.Printf("Error occured: %v", err)
+		logger.Error("ALS error", "err", err)
 	}
 }
 
@@ -58,16 +58,17 @@ func makeCMatrix(mat *DenseMatrix) *DenseMatrix {
 
 // create X and Y matrices for the ALS algorithm.
 func makeXY(mat *DenseMatrix, n_factors int, max_rating float64, seed int) (X, Y *DenseMatrix) {
-	rand.Seed(int64(seed))
+	// a source of its own, since the global one can't be seeded anymore (Go 1.24)
+	r := rand.New(rand.NewSource(int64(seed)))
 	rows := mat.Rows()
 	cols := mat.Cols()
 	X_data := make([]float64, rows*n_factors)
 	Y_data := make([]float64, cols*n_factors)
 	for i := 0; i < len(X_data); i++ {
-		X_data[i] = max_rating * rand.Float64()
+		X_data[i] = max_rating * r.Float64()
 	}
 	for j := 0; j < len(Y_data); j++ {
-		Y_data[j] = max_rating * rand.Float64()
+		Y_data[j] = max_rating * r.Float64()
 	}
 	X = MakeDenseMatrix(X_data, rows, n_factors)
 	Y = MakeDenseMatrix(Y_data, n_factors, cols)
@@ -122,7 +123,7 @@ func getErrorInline(W, q, X, Y *DenseMatrix) float64 {
 // a function to set the values for a given row
 func setRow(mat *DenseMatrix, which int, row []float64) *DenseMatrix {
 	if mat.Cols() != len(row) {
-		fmt.Println("The row to set needs to be the same dimension as the matrix")
+		logger.Error("The row to set needs to be the same dimension as the matrix", "cols", mat.Cols(), "len", len(row))
 	}
 	// iterate over columns to set the values for a selected row
 	for i := 0; i < mat.Cols(); i++ {
@@ -134,7 +135,7 @@ func setRow(mat *DenseMatrix, which int, row []float64) *DenseMatrix {
 // a function to set the values for a given column
 func setCol(mat *DenseMatrix, which int, col []float64) *DenseMatrix {
 	if mat.Rows() != len(col) {
-		fmt.Println("The column to set needs to be the same dimension as the matrix")
+		logger.Error("The column to set needs to be the same dimension as the matrix", "rows", mat.Rows(), "len", len(col))
 	}
 	// iterate over rows to set the values for a selected columns
 	for i := 0; i < mat.Rows(); i++ {
@@ -215,8 +216,9 @@ func Train(Q *DenseMatrix, n_factors, iterations int, lambda float64) (*DenseMat
 		// Calculate the error values at each iteration
 		error_value := getErrorInline(W, Q, X, Y)
 		errors = append(errors, error_value)
+		logger.Debug("ALS iteration", "iteration", ii+1, "error", error_value)
 	}
-	fmt.Printf("\nFinal Error value of: %v\n", errors[len(errors)-1])
+	logger.Info("ALS trained", "error", errors[len(errors)-1])
 	weighted_Qhat, _ := X.TimesDense(Y)
 	return weighted_Qhat, errors[len(errors)-1]
 }
@@ -263,6 +265,7 @@ func TrainImplicit(R *DenseMatrix, n_factors, iterations int, lambda float64) *D
 			new_col, _ := x_t_c_xInv.TimesDense(y_tosolve)
 			Y = setCol(Y, i, new_col.Array())
 		}
+		logger.Debug("implicit ALS iteration", "iteration", ii+1)
 	}
 	weighted_Qhat, _ := X.TimesDense(Y)
 	return weighted_Qhat
@@ -0,0 +1,217 @@
+package ALS
+
+import (
+	"errors"
+	"math"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// Element-wise ALS (eALS) for implicit feedback, as outlined in He et al.
+// "Fast Matrix Factorization for Online Recommendation with Implicit Feedback" (SIGIR 2016).
+// Observed entries have a target of 1 and weight W, while missing entries have a target of 0
+// and a confidence C[i] that depends on the popularity of item i.
+type EALS struct {
+	// user factors (users x factors) and item factors (factors x items), same layout as makeXY.
+	X, Y *DenseMatrix
+	// confidence for missing entries of each item.
+	C []float64
+	// weight for observed entries, and the regularization parameter.
+	W, Lambda float64
+
+	userItems [][]int
+	itemUsers [][]int
+	// caches of sum_u x_u * x_u^T and sum_i c_i * y_i * y_i^T, kept in sync on every update.
+	sp, sq *DenseMatrix
+}
+
+// Weights the missing data of each item by its popularity:
+// c_i = c0 * f_i^alpha / sum_j(f_j^alpha), where f_i is the number of users that interacted with item i.
+func popularityWeights(itemUsers [][]int, c0, alpha float64) []float64 {
+	weights := make([]float64, len(itemUsers))
+	total := float64(0)
+	for i, users := range itemUsers {
+		weights[i] = math.Pow(float64(len(users)), alpha)
+		total += weights[i]
+	}
+	for i := 0; i < len(weights); i++ {
+		if total > 0 {
+			weights[i] = c0 * weights[i] / total
+		}
+	}
+	return weights
+}
+
+// Params: the interaction matrix, number of factors, iterations, lambda, the overall weight of the
+// missing data (c0) and the popularity exponent (alpha). The paper recommends c0 = 512 and alpha = 0.4.
+// Non-zero entries of R are treated as observed interactions. Returns the trained model.
+func TrainEALS(R *DenseMatrix, n_factors, iterations int, lambda, c0, alpha float64) *EALS {
+	model := &EALS{
+		W:         1,
+		Lambda:    lambda,
+		userItems: make([][]int, R.Rows()),
+		itemUsers: make([][]int, R.Cols()),
+	}
+	for u := 0; u < R.Rows(); u++ {
+		for i := 0; i < R.Cols(); i++ {
+			val := R.Get(u, i)
+			if val != 0 && !math.IsNaN(val) {
+				model.userItems[u] = append(model.userItems[u], i)
+				model.itemUsers[i] = append(model.itemUsers[i], u)
+			}
+		}
+	}
+	model.C = popularityWeights(model.itemUsers, c0, alpha)
+	model.X, model.Y = makeXY(R, n_factors, 0.1, 47)
+	model.sp = model.userCache()
+	model.sq = model.itemCache()
+
+	for ii := 0; ii < iterations; ii++ {
+		for u := 0; u < R.Rows(); u++ {
+			model.updateUser(u)
+		}
+		for i := 0; i < R.Cols(); i++ {
+			model.updateItem(i)
+		}
+		logger.Debug("eALS iteration", "iteration", ii+1)
+	}
+	return model
+}
+
+// dot product of user u's and item i's factors.
+func (m *EALS) predict(u, i int) float64 {
+	pred := float64(0)
+	for f := 0; f < m.X.Cols(); f++ {
+		pred += m.X.Get(u, f) * m.Y.Get(f, i)
+	}
+	return pred
+}
+
+// S^q = sum_i c_i * y_i * y_i^T, used by the user updates.
+func (m *EALS) itemCache() *DenseMatrix {
+	k := m.X.Cols()
+	S := Zeros(k, k)
+	for i := 0; i < m.Y.Cols(); i++ {
+		addOuter(S, m.Y.ColCopy(i), m.C[i])
+	}
+	return S
+}
+
+// S^p = sum_u x_u * x_u^T, used by the item updates.
+func (m *EALS) userCache() *DenseMatrix {
+	k := m.X.Cols()
+	S := Zeros(k, k)
+	for u := 0; u < m.X.Rows(); u++ {
+		addOuter(S, m.X.RowCopy(u), 1)
+	}
+	return S
+}
+
+// adds weight * v * v^T to the cache S.
+func addOuter(S *DenseMatrix, v []float64, weight float64) {
+	for f := 0; f < len(v); f++ {
+		for g := 0; g < len(v); g++ {
+			S.Set(f, g, S.Get(f, g)+weight*v[f]*v[g])
+		}
+	}
+}
+
+// updates the factors of user u one coordinate at a time.
+func (m *EALS) updateUser(u int) {
+	old := m.X.RowCopy(u)
+	items := m.userItems[u]
+	preds := make([]float64, len(items))
+	for idx, i := range items {
+		preds[idx] = m.predict(u, i)
+	}
+	for f := 0; f < m.X.Cols(); f++ {
+		numer, denom := float64(0), float64(0)
+		for idx, i := range items {
+			y_if := m.Y.Get(f, i)
+			// prediction without the contribution of factor f
+			preds[idx] -= m.X.Get(u, f) * y_if
+			numer += (m.W - (m.W-m.C[i])*preds[idx]) * y_if
+			denom += (m.W - m.C[i]) * y_if * y_if
+		}
+		for k := 0; k < m.X.Cols(); k++ {
+			if k != f {
+				numer -= m.X.Get(u, k) * m.sq.Get(k, f)
+			}
+		}
+		denom += m.sq.Get(f, f) + m.Lambda
+		m.X.Set(u, f, numer/denom)
+		for idx, i := range items {
+			preds[idx] += m.X.Get(u, f) * m.Y.Get(f, i)
+		}
+	}
+	addOuter(m.sp, old, -1)
+	addOuter(m.sp, m.X.RowCopy(u), 1)
+}
+
+// updates the factors of item i one coordinate at a time.
+func (m *EALS) updateItem(i int) {
+	old := m.Y.ColCopy(i)
+	users := m.itemUsers[i]
+	preds := make([]float64, len(users))
+	for idx, u := range users {
+		preds[idx] = m.predict(u, i)
+	}
+	for f := 0; f < m.Y.Rows(); f++ {
+		numer, denom := float64(0), float64(0)
+		for idx, u := range users {
+			x_uf := m.X.Get(u, f)
+			preds[idx] -= x_uf * m.Y.Get(f, i)
+			numer += (m.W - (m.W-m.C[i])*preds[idx]) * x_uf
+			denom += (m.W - m.C[i]) * x_uf * x_uf
+		}
+		for k := 0; k < m.Y.Rows(); k++ {
+			if k != f {
+				numer -= m.C[i] * m.Y.Get(k, i) * m.sp.Get(k, f)
+			}
+		}
+		denom += m.C[i]*m.sp.Get(f, f) + m.Lambda
+		m.Y.Set(f, i, numer/denom)
+		for idx, u := range users {
+			preds[idx] += m.X.Get(u, f) * m.Y.Get(f, i)
+		}
+	}
+	addOuter(m.sq, old, -m.C[i])
+	addOuter(m.sq, m.Y.ColCopy(i), m.C[i])
+}
+
+// Incrementally updates the model with a new user-item interaction, following the online
+// update rule of eALS: only the factors of the user and the item are refreshed.
+// Item confidences are kept fixed. Error if out of range.
+func (m *EALS) Update(user, item int) error {
+	if user >= m.X.Rows() || item >= m.Y.Cols() || user < 0 || item < 0 {
+		return errors.New("User/Product index out of range")
+	}
+	seen := false
+	for _, i := range m.userItems[user] {
+		if i == item {
+			seen = true
+		}
+	}
+	if !seen {
+		m.userItems[user] = append(m.userItems[user], item)
+		m.itemUsers[item] = append(m.itemUsers[item], user)
+	}
+	m.updateUser(user)
+	m.updateItem(item)
+	return nil
+}
+
+// Returns the predicted preference for a given user-product pair. Error if out of range.
+func (m *EALS) Predict(user, product int) (float64, error) {
+	if user >= m.X.Rows() || product >= m.Y.Cols() || user < 0 || product < 0 {
+		return 0.0, errors.New("User/Product index out of range")
+	}
+	return m.predict(user, product), nil
+}
+
+// Returns the full prediction matrix, for use with Predict and GetTopNRecommendations.
+func (m *EALS) Qhat() *DenseMatrix {
+	Qhat, err := m.X.TimesDense(m.Y)
+	errcheck(err)
+	return Qhat
+}
@@ -0,0 +1,58 @@
+package ALS
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func TestPopularityWeights(t *testing.T) {
+	itemUsers := [][]int{{0, 1, 2, 3}, {0}, {}}
+	weights := popularityWeights(itemUsers, 10, 0.5)
+	// 4^0.5 = 2, 1^0.5 = 1 and an unseen item gets no weight.
+	Assert(t, weights[0] == 2*weights[1], weights)
+	Assert(t, weights[2] == 0, weights)
+	Assert(t, weights[0]+weights[1]+weights[2] == 10, weights)
+}
+
+func TestEALS(t *testing.T) {
+	R := MakeDenseMatrix([]float64{1, 1, 0, 0,
+		1, 1, 0, 0,
+		0, 0, 1, 1,
+		0, 1, 1, 1}, 4, 4)
+
+	model := TrainEALS(R, 3, 20, 0.01, 1, 0.4)
+	Qhat := model.Qhat()
+	Assert(t, Qhat.Rows() == 4 && Qhat.Cols() == 4)
+	// observed interactions should be scored above the missing ones
+	Assert(t, Qhat.Get(0, 0) > Qhat.Get(0, 2), Qhat)
+	Assert(t, Qhat.Get(2, 3) > Qhat.Get(2, 0), Qhat)
+
+	pred, err := model.Predict(1, 1)
+	Assert(t, err == nil && pred == Qhat.Get(1, 1))
+	_, err = model.Predict(4, 0)
+	Assert(t, err != nil)
+}
+
+func TestEALSUpdate(t *testing.T) {
+	R := MakeDenseMatrix([]float64{1, 1, 0, 0,
+		1, 1, 0, 0,
+		0, 0, 1, 1,
+		0, 1, 1, 1}, 4, 4)
+
+	model := TrainEALS(R, 3, 20, 0.01, 1, 0.4)
+	before, _ := model.Predict(0, 3)
+	for ii := 0; ii < 3; ii++ {
+		err := model.Update(0, 3)
+		Assert(t, err == nil)
+	}
+	after, _ := model.Predict(0, 3)
+	Assert(t, after > before, before, after)
+	// the caches kept on the model should match a full recomputation
+	sq := model.itemCache()
+	for idx, val := range sq.Array() {
+		Assert(t, math.Abs(val-model.sq.Array()[idx]) < 1e-9, sq, model.sq)
+	}
+	Assert(t, model.Update(0, 4) != nil)
+}
@@ -0,0 +1,238 @@
+package ALS
+
+import (
+	"errors"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// Hybrid matrix factorization with user and item side features, in the style of LightFM / SVDFeature.
+// Each user (item) is described by a list of feature indices, e.g. country or age bucket (category or brand),
+// and its embedding is the sum of the embeddings of its features. Items that were never rated can therefore
+// still be scored from their metadata alone.
+type Hybrid struct {
+	// feature indices of each user (row of Q) and each item (column of Q)
+	UserFeatures, ItemFeatures [][]int
+	// user feature embeddings (user features x factors) and item feature embeddings (factors x item features)
+	U, V *DenseMatrix
+}
+
+// returns the number of features referenced, i.e. the largest feature index + 1.
+// Error if an index is negative.
+func numFeatures(features [][]int) (int, error) {
+	n := 0
+	for _, row := range features {
+		for _, f := range row {
+			if f < 0 {
+				return 0, errors.New("Feature indices can't be negative")
+			}
+			if f+1 > n {
+				n = f + 1
+			}
+		}
+	}
+	return n, nil
+}
+
+// Adds an identity feature to every row, so that users/items with enough data can move away from
+// the embedding given by their metadata. The identity of row k gets feature index n + k,
+// where n is the number of features already in use.
+// Error if a feature index is negative.
+func IdentityFeatures(features [][]int) ([][]int, error) {
+	n, err := numFeatures(features)
+	if err != nil {
+		return nil, err
+	}
+	withIdentity := make([][]int, len(features))
+	for k, row := range features {
+		withIdentity[k] = append(append([]int{}, row...), n+k)
+	}
+	return withIdentity, nil
+}
+
+// Params: the user/product matrix, the features of each user and each product, number of factors, iterations,
+// and lambda. Each feature embedding is solved in turn with the others fixed, alternating between user and
+// item features as in Train. Returns the trained model, the final error calculation (float64), and an error
+// if the feature lists don't match the dimensions of Q or a feature index is negative.
+func TrainHybrid(Q *DenseMatrix, userFeatures, itemFeatures [][]int, n_factors, iterations int, lambda float64) (*Hybrid, float64, error) {
+	if len(userFeatures) != Q.Rows() || len(itemFeatures) != Q.Cols() {
+		return nil, 0, errors.New("Need one feature list per user and per product")
+	}
+	n_user_features, err := numFeatures(userFeatures)
+	if err != nil {
+		return nil, 0, err
+	}
+	n_item_features, err := numFeatures(itemFeatures)
+	if err != nil {
+		return nil, 0, err
+	}
+	model := &Hybrid{UserFeatures: userFeatures, ItemFeatures: itemFeatures}
+	model.U, model.V = makeXY(Zeros(n_user_features, n_item_features), n_factors, 0.1, 47)
+	W := makeWeightMatrix(Q)
+
+	// users having each user feature, and items having each item feature
+	usersWith := make([][]int, model.U.Rows())
+	for u, row := range userFeatures {
+		for _, f := range row {
+			usersWith[f] = append(usersWith[f], u)
+		}
+	}
+	itemsWith := make([][]int, model.V.Cols())
+	for i, row := range itemFeatures {
+		for _, f := range row {
+			itemsWith[f] = append(itemsWith[f], i)
+		}
+	}
+
+	error_value := float64(0)
+	for ii := 0; ii < iterations; ii++ {
+		// solve for the user feature embeddings
+		items := model.itemMatrix()
+		for f := 0; f < model.U.Rows(); f++ {
+			users := model.userMatrix()
+			A := Eye(n_factors)
+			A.Scale(lambda)
+			b := Zeros(n_factors, 1)
+			for _, u := range usersWith[f] {
+				for i := 0; i < Q.Cols(); i++ {
+					if W.Get(u, i) == 0 {
+						continue
+					}
+					// rating left to explain once the other features of u are accounted for
+					residual := Q.Get(u, i)
+					for k := 0; k < n_factors; k++ {
+						residual -= (users.Get(u, k) - model.U.Get(f, k)) * items.Get(k, i)
+					}
+					for k := 0; k < n_factors; k++ {
+						b.Set(k, 0, b.Get(k, 0)+residual*items.Get(k, i))
+						for l := 0; l < n_factors; l++ {
+							A.Set(k, l, A.Get(k, l)+items.Get(k, i)*items.Get(l, i))
+						}
+					}
+				}
+			}
+			AInv, err := A.Inverse()
+			errcheck(err)
+			new_row, err := AInv.TimesDense(b)
+			errcheck(err)
+			model.U = setRow(model.U, f, new_row.Array())
+		}
+		// now alternate to solve for the item feature embeddings
+		users := model.userMatrix()
+		for f := 0; f < model.V.Cols(); f++ {
+			items := model.itemMatrix()
+			A := Eye(n_factors)
+			A.Scale(lambda)
+			b := Zeros(n_factors, 1)
+			for _, i := range itemsWith[f] {
+				for u := 0; u < Q.Rows(); u++ {
+					if W.Get(u, i) == 0 {
+						continue
+					}
+					residual := Q.Get(u, i)
+					for k := 0; k < n_factors; k++ {
+						residual -= users.Get(u, k) * (items.Get(k, i) - model.V.Get(k, f))
+					}
+					for k := 0; k < n_factors; k++ {
+						b.Set(k, 0, b.Get(k, 0)+residual*users.Get(u, k))
+						for l := 0; l < n_factors; l++ {
+							A.Set(k, l, A.Get(k, l)+users.Get(u, k)*users.Get(u, l))
+						}
+					}
+				}
+			}
+			AInv, err := A.Inverse()
+			errcheck(err)
+			new_col, err := AInv.TimesDense(b)
+			errcheck(err)
+			model.V = setCol(model.V, f, new_col.Array())
+		}
+		error_value = getErrorInline(W, Q, model.userMatrix(), model.itemMatrix())
+		logger.Debug("hybrid ALS iteration", "iteration", ii+1, "error", error_value)
+	}
+	return model, error_value, nil
+}
+
+// Sums the user feature embeddings for the given features. Unknown features are ignored.
+func (m *Hybrid) UserEmbedding(features []int) []float64 {
+	embedding := make([]float64, m.U.Cols())
+	for _, f := range features {
+		if f >= 0 && f < m.U.Rows() {
+			for k := 0; k < len(embedding); k++ {
+				embedding[k] += m.U.Get(f, k)
+			}
+		}
+	}
+	return embedding
+}
+
+// Sums the item feature embeddings for the given features. Unknown features are ignored.
+func (m *Hybrid) ItemEmbedding(features []int) []float64 {
+	embedding := make([]float64, m.V.Rows())
+	for _, f := range features {
+		if f >= 0 && f < m.V.Cols() {
+			for k := 0; k < len(embedding); k++ {
+				embedding[k] += m.V.Get(k, f)
+			}
+		}
+	}
+	return embedding
+}
+
+// user embeddings for all users in the training data (users x factors)
+func (m *Hybrid) userMatrix() *DenseMatrix {
+	users := Zeros(len(m.UserFeatures), m.U.Cols())
+	for u, features := range m.UserFeatures {
+		users = setRow(users, u, m.UserEmbedding(features))
+	}
+	return users
+}
+
+// item embeddings for all items in the training data (factors x items)
+func (m *Hybrid) itemMatrix() *DenseMatrix {
+	items := Zeros(m.V.Rows(), len(m.ItemFeatures))
+	for i, features := range m.ItemFeatures {
+		items = setCol(items, i, m.ItemEmbedding(features))
+	}
+	return items
+}
+
+// Scores a user-item pair from their features alone. Use this for users or items that were not
+// part of the training data.
+func (m *Hybrid) Score(userFeatures, itemFeatures []int) float64 {
+	user := m.UserEmbedding(userFeatures)
+	item := m.ItemEmbedding(itemFeatures)
+	score := float64(0)
+	for k := 0; k < len(user); k++ {
+		score += user[k] * item[k]
+	}
+	return score
+}
+
+// Returns the predicted rating for a user-product pair of the training data. Error if out of range.
+func (m *Hybrid) Predict(user, product int) (float64, error) {
+	if user >= len(m.UserFeatures) || product >= len(m.ItemFeatures) || user < 0 || product < 0 {
+		return 0.0, errors.New("User/Product index out of range")
+	}
+	return m.Score(m.UserFeatures[user], m.ItemFeatures[product]), nil
+}
+
+// Scores new items, described by their features only, for a user of the training data.
+// Returns the scores in the same order as the items. Error if out of range.
+func (m *Hybrid) ScoreNewItems(user int, itemFeatures [][]int) ([]float64, error) {
+	if user >= len(m.UserFeatures) || user < 0 {
+		return nil, errors.New("User index out of range")
+	}
+	scores := make([]float64, len(itemFeatures))
+	for idx, features := range itemFeatures {
+		scores[idx] = m.Score(m.UserFeatures[user], features)
+	}
+	return scores, nil
+}
+
+// Returns the full prediction matrix, for use with Predict and GetTopNRecommendations.
+func (m *Hybrid) Qhat() *DenseMatrix {
+	Qhat, err := m.userMatrix().TimesDense(m.itemMatrix())
+	errcheck(err)
+	return Qhat
+}
@@ -0,0 +1,45 @@
+package ALS
+
+import (
+	"testing"
+)
+
+func TestIdentityFeatures(t *testing.T) {
+	features, err := IdentityFeatures([][]int{{0}, {1}, {0, 2}})
+	Assert(t, err == nil && len(features[2]) == 3 && features[2][2] == 5, features)
+	n, err := numFeatures(features)
+	Assert(t, err == nil && n == 6)
+	_, err = IdentityFeatures([][]int{{0}, {-1}})
+	Assert(t, err != nil)
+}
+
+func TestHybrid(t *testing.T) {
+	// users 0,1 like genre 0 (items 0,1), users 2,3 like genre 1 (items 2,3)
+	Q := MakeRatingMatrix([]float64{5, 4, 1, 0,
+		4, 0, 1, 1,
+		1, 1, 5, 4,
+		0, 1, 4, 5}, 4, 4)
+	userFeatures, _ := IdentityFeatures([][]int{{0}, {0}, {1}, {1}})
+	itemFeatures, _ := IdentityFeatures([][]int{{0}, {0}, {1}, {1}})
+
+	model, err_value, err := TrainHybrid(Q, userFeatures, itemFeatures, 2, 15, 0.01)
+	Assert(t, err == nil)
+	Assert(t, err_value < 5, err_value)
+
+	pred, err := model.Predict(1, 1)
+	Assert(t, err == nil && pred > 3, pred)
+	Qhat := model.Qhat()
+	Assert(t, Qhat.Get(1, 1) == pred)
+
+	// a brand new item of genre 1 should be scored from its genre alone
+	scores, err := model.ScoreNewItems(3, [][]int{{0}, {1}})
+	Assert(t, err == nil && scores[1] > scores[0], scores)
+
+	_, _, err = TrainHybrid(Q, userFeatures[:2], itemFeatures, 2, 1, 0.01)
+	Assert(t, err != nil)
+	// negative feature indices
+	_, _, err = TrainHybrid(Q, [][]int{{0}, {-1}, {1}, {1}}, itemFeatures, 2, 1, 0.01)
+	Assert(t, err != nil)
+	_, _, err = TrainHybrid(Q, userFeatures, [][]int{{0}, {0}, {1}, {-2}}, 2, 1, 0.01)
+	Assert(t, err != nil)
+}
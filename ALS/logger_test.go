@@ -0,0 +1,29 @@
+package ALS
+
+import (
+	"errors"
+	"testing"
+)
+
+// keeps the messages logged at each level
+type recordingLogger map[string][]string
+
+func (r recordingLogger) Debug(msg string, args ...interface{}) { r["debug"] = append(r["debug"], msg) }
+func (r recordingLogger) Info(msg string, args ...interface{})  { r["info"] = append(r["info"], msg) }
+func (r recordingLogger) Error(msg string, args ...interface{}) { r["error"] = append(r["error"], msg) }
+
+func TestLogger(t *testing.T) {
+	logs := recordingLogger{}
+	SetLogger(logs)
+	defer SetLogger(nil)
+	Q := MakeRatingMatrix([]float64{5, 3, 0, 1, 4, 0, 0, 1, 1, 1, 0, 5}, 3, 4)
+	Train(Q, 2, 3, 0.1)
+	Assert(t, len(logs["debug"]) == 3 && len(logs["info"]) == 1, logs)
+	errcheck(errors.New("oops"))
+	Assert(t, len(logs["error"]) == 1, logs)
+
+	// silent again
+	SetLogger(nil)
+	errcheck(errors.New("oops"))
+	Assert(t, len(logs["error"]) == 1, logs)
+}
@@ -0,0 +1,149 @@
+package ALS
+
+import (
+	"errors"
+	"math"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// Context-aware factorization of a user x product x context tensor, using the CP/PARAFAC decomposition:
+// T[c](u, i) ~ sum_f A(u, f) * B(i, f) * C(c, f).
+// The tensor is given as one user/product matrix per context (device, time of day, ...). Several context
+// variables can be combined into a single index, e.g. device * 24 + hour.
+type TensorModel struct {
+	// user factors (users x factors), product factors (products x factors) and context factors (contexts x factors)
+	A, B, C *DenseMatrix
+}
+
+// an observed entry of the tensor
+type tensorEntry struct {
+	index [3]int
+	value float64
+}
+
+// Params: the user/product matrices for each context, number of factors, iterations, and lambda value.
+// Like Train, 0 or NaN entries are missing. Each mode is solved in turn with the other two fixed.
+// Returns the trained model and the final error calculation (float64). Error if the matrices differ in size.
+func TrainTensor(T []*DenseMatrix, n_factors, iterations int, lambda float64) (*TensorModel, float64, error) {
+	if len(T) == 0 {
+		return nil, 0, errors.New("Need at least one context")
+	}
+	dims := [3]int{T[0].Rows(), T[0].Cols(), len(T)}
+	// observed entries along each mode, e.g. byMode[0][u] are the entries of user u.
+	byMode := [3][][]tensorEntry{}
+	for mode := 0; mode < 3; mode++ {
+		byMode[mode] = make([][]tensorEntry, dims[mode])
+	}
+	for c, mat := range T {
+		if mat.Rows() != dims[0] || mat.Cols() != dims[1] {
+			return nil, 0, errors.New("All context matrices need the same dimensions")
+		}
+		for u := 0; u < mat.Rows(); u++ {
+			for i := 0; i < mat.Cols(); i++ {
+				val := mat.Get(u, i)
+				if val != 0 && !math.IsNaN(val) {
+					entry := tensorEntry{[3]int{u, i, c}, val}
+					for mode := 0; mode < 3; mode++ {
+						byMode[mode][entry.index[mode]] = append(byMode[mode][entry.index[mode]], entry)
+					}
+				}
+			}
+		}
+	}
+
+	model := &TensorModel{}
+	X, Y := makeXY(Zeros(dims[0], dims[1]), n_factors, 1, 47)
+	// contexts are initialized like the users of a matrix with no products
+	model.C, _ = makeXY(Zeros(dims[2], 0), n_factors, 1, 48)
+	model.A, model.B = X, Y.Transpose()
+	factors := [3]*DenseMatrix{model.A, model.B, model.C}
+
+	error_value := float64(0)
+	for ii := 0; ii < iterations; ii++ {
+		for mode := 0; mode < 3; mode++ {
+			for idx := 0; idx < dims[mode]; idx++ {
+				// scaled identity matrix
+				zz := Eye(n_factors)
+				zz.Scale(lambda)
+				zt := Zeros(n_factors, 1)
+				for _, entry := range byMode[mode][idx] {
+					// element-wise product of the factors of the other two modes
+					z := make([]float64, n_factors)
+					for f := 0; f < n_factors; f++ {
+						z[f] = 1
+						for other := 0; other < 3; other++ {
+							if other != mode {
+								z[f] *= factors[other].Get(entry.index[other], f)
+							}
+						}
+					}
+					for f := 0; f < n_factors; f++ {
+						zt.Set(f, 0, zt.Get(f, 0)+entry.value*z[f])
+						for g := 0; g < n_factors; g++ {
+							zz.Set(f, g, zz.Get(f, g)+z[f]*z[g])
+						}
+					}
+				}
+				zzInv, err := zz.Inverse()
+				errcheck(err)
+				new_row, err := zzInv.TimesDense(zt)
+				errcheck(err)
+				factors[mode] = setRow(factors[mode], idx, new_row.Array())
+			}
+		}
+		// Calculate the error value at each iteration
+		error_value = 0
+		for u := 0; u < dims[0]; u++ {
+			for _, entry := range byMode[0][u] {
+				e := entry.value - model.predict(entry.index[0], entry.index[1], entry.index[2])
+				error_value += e * e
+			}
+		}
+		logger.Debug("tensor ALS iteration", "iteration", ii+1, "error", error_value)
+	}
+	return model, error_value, nil
+}
+
+func (m *TensorModel) predict(user, product, context int) float64 {
+	pred := float64(0)
+	for f := 0; f < m.A.Cols(); f++ {
+		pred += m.A.Get(user, f) * m.B.Get(product, f) * m.C.Get(context, f)
+	}
+	return pred
+}
+
+// Returns the predicted value for a user-product pair in the given context. Error if out of range.
+func (m *TensorModel) Predict(user, product, context int) (float64, error) {
+	if user >= m.A.Rows() || product >= m.B.Rows() || context >= m.C.Rows() || user < 0 || product < 0 || context < 0 {
+		return 0.0, errors.New("User/Product/Context index out of range")
+	}
+	return m.predict(user, product, context), nil
+}
+
+// Returns the prediction matrix (users x products) for the given context. Error if out of range.
+func (m *TensorModel) Qhat(context int) (*DenseMatrix, error) {
+	if context >= m.C.Rows() || context < 0 {
+		return nil, errors.New("Context index out of range")
+	}
+	Qhat := Zeros(m.A.Rows(), m.B.Rows())
+	for u := 0; u < Qhat.Rows(); u++ {
+		for i := 0; i < Qhat.Cols(); i++ {
+			Qhat.Set(u, i, m.predict(u, i, context))
+		}
+	}
+	return Qhat, nil
+}
+
+// Returns best n recommendations for a user in the given context, skipping the products the user
+// already has in that context. Same as GetTopNRecommendations on the context's slice of T.
+func (m *TensorModel) GetTopNRecommendations(T []*DenseMatrix, user, context, n int, products []string) ([]string, error) {
+	Qhat, err := m.Qhat(context)
+	if err != nil {
+		return nil, err
+	}
+	if context >= len(T) {
+		return nil, errors.New("Context index out of range")
+	}
+	return GetTopNRecommendations(T[context].Copy(), Qhat, user, n, products)
+}
@@ -0,0 +1,40 @@
+package ALS
+
+import (
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func TestTensor(t *testing.T) {
+	// context 0 (mobile): users like products 0 and 1. context 1 (desktop): users like products 2 and 3.
+	mobile := MakeDenseMatrix([]float64{5, 4, 1, 0,
+		4, 5, 0, 1,
+		5, 0, 1, 1}, 3, 4)
+	desktop := MakeDenseMatrix([]float64{1, 0, 5, 4,
+		1, 1, 4, 5,
+		0, 1, 5, 0}, 3, 4)
+	T := []*DenseMatrix{mobile, desktop}
+
+	model, err_value, err := TrainTensor(T, 3, 20, 0.01)
+	Assert(t, err == nil)
+	Assert(t, err_value < 2, err_value)
+
+	pred, err := model.Predict(2, 1, 0)
+	Assert(t, err == nil)
+	other, _ := model.Predict(2, 1, 1)
+	Assert(t, pred > other, pred, other)
+
+	products := []string{"a", "b", "c", "d"}
+	recs, err := model.GetTopNRecommendations(T, 2, 0, 1, products)
+	Assert(t, err == nil && recs[0] == "b", recs)
+	recs, err = model.GetTopNRecommendations(T, 2, 1, 1, products)
+	Assert(t, err == nil && recs[0] == "d", recs)
+	// the input tensor should be left as is
+	Assert(t, mobile.Get(0, 0) == 5)
+
+	_, err = model.Qhat(2)
+	Assert(t, err != nil)
+	_, _, err = TrainTensor([]*DenseMatrix{mobile, Zeros(2, 2)}, 3, 1, 0.01)
+	Assert(t, err != nil)
+}
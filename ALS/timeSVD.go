@@ -0,0 +1,156 @@
+package ALS
+
+import (
+	"errors"
+	"math"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+const secondsPerDay = 86400
+
+// Time-aware factorization with drifting biases, following the timeSVD model of Koren ("Collaborative
+// Filtering with Temporal Dynamics", KDD 2009), without the implicit feedback term of timeSVD++.
+// A rating made at time t is predicted as
+// mu + b_u + alpha_u * dev_u(t) + b_i + b_i,Bin(t) + x_u * y_i
+// where dev_u(t) = sign(t - t_u) * |t - t_u|^beta measures, in days, how far t is from the user's mean rating date.
+type TimeSVD struct {
+	// global mean rating
+	Mu float64
+	// user bias and user drift (alpha_u), with the mean rating time of each user.
+	UserBias, UserDrift, MeanTime []float64
+	// static item bias, and the item bias for each time bin (items x bins).
+	ItemBias []float64
+	BinBias  *DenseMatrix
+	// user factors (users x factors) and item factors (factors x items)
+	X, Y *DenseMatrix
+	// the time range covered by the bins, and the drift exponent (0.4 in the paper)
+	Start, End int64
+	Beta       float64
+}
+
+// Params: the ratings (see LoadRatings), number of factors, number of time bins, iterations, lambda, the
+// learning rate, and the learning rate of the user drifts. The drift multiplies a (possibly large) deviation
+// in days, so its learning rate needs to be much smaller, e.g. 1% of the learning rate.
+// The model is trained with stochastic gradient descent over the ratings.
+// Returns the trained model. Error if there are no ratings or no bins.
+func TrainTimeSVD(ratings []Rating, n_factors, n_bins, iterations int, lambda, learning_rate, drift_rate float64) (*TimeSVD, error) {
+	if len(ratings) == 0 || n_bins < 1 {
+		return nil, errors.New("Need at least one rating and one time bin")
+	}
+	n_users, n_items := 0, 0
+	model := &TimeSVD{Start: ratings[0].Time, End: ratings[0].Time, Beta: 0.4}
+	for _, r := range ratings {
+		if r.User+1 > n_users {
+			n_users = r.User + 1
+		}
+		if r.Product+1 > n_items {
+			n_items = r.Product + 1
+		}
+		if r.Time < model.Start {
+			model.Start = r.Time
+		}
+		if r.Time > model.End {
+			model.End = r.Time
+		}
+		model.Mu += r.Value
+	}
+	model.Mu /= float64(len(ratings))
+	model.UserBias = make([]float64, n_users)
+	model.UserDrift = make([]float64, n_users)
+	model.MeanTime = make([]float64, n_users)
+	model.ItemBias = make([]float64, n_items)
+	model.BinBias = Zeros(n_items, n_bins)
+	model.X, model.Y = makeXY(Zeros(n_users, n_items), n_factors, 0.1, 47)
+
+	counts := make([]float64, n_users)
+	for _, r := range ratings {
+		model.MeanTime[r.User] += float64(r.Time)
+		counts[r.User]++
+	}
+	for u := 0; u < n_users; u++ {
+		if counts[u] > 0 {
+			model.MeanTime[u] /= counts[u]
+		}
+	}
+
+	for ii := 0; ii < iterations; ii++ {
+		for _, r := range ratings {
+			u, i := r.User, r.Product
+			bin := model.bin(r.Time)
+			dev := model.dev(u, r.Time)
+			e := r.Value - model.predict(u, i, r.Time)
+
+			model.UserBias[u] += learning_rate * (e - lambda*model.UserBias[u])
+			model.UserDrift[u] += drift_rate * (e*dev - lambda*model.UserDrift[u])
+			model.ItemBias[i] += learning_rate * (e - lambda*model.ItemBias[i])
+			model.BinBias.Set(i, bin, model.BinBias.Get(i, bin)+learning_rate*(e-lambda*model.BinBias.Get(i, bin)))
+			for f := 0; f < n_factors; f++ {
+				x_uf, y_fi := model.X.Get(u, f), model.Y.Get(f, i)
+				model.X.Set(u, f, x_uf+learning_rate*(e*y_fi-lambda*x_uf))
+				model.Y.Set(f, i, y_fi+learning_rate*(e*x_uf-lambda*y_fi))
+			}
+		}
+		logger.Debug("timeSVD epoch", "epoch", ii+1)
+	}
+	return model, nil
+}
+
+// returns the time bin of t. Times outside of the training range fall into the first/last bin.
+func (m *TimeSVD) bin(t int64) int {
+	n_bins := m.BinBias.Cols()
+	if t <= m.Start {
+		return 0
+	}
+	if t >= m.End {
+		return n_bins - 1
+	}
+	return int(float64(t-m.Start) / float64(m.End-m.Start+1) * float64(n_bins))
+}
+
+// dev_u(t) = sign(t - t_u) * |t - t_u|^beta, in days.
+func (m *TimeSVD) dev(user int, t int64) float64 {
+	days := (float64(t) - m.MeanTime[user]) / secondsPerDay
+	if days < 0 {
+		return -math.Pow(-days, m.Beta)
+	}
+	return math.Pow(days, m.Beta)
+}
+
+func (m *TimeSVD) predict(user, product int, t int64) float64 {
+	pred := m.Mu + m.UserBias[user] + m.UserDrift[user]*m.dev(user, t) +
+		m.ItemBias[product] + m.BinBias.Get(product, m.bin(t))
+	for f := 0; f < m.X.Cols(); f++ {
+		pred += m.X.Get(user, f) * m.Y.Get(f, product)
+	}
+	return pred
+}
+
+// Returns the predicted rating of a user-product pair at the given time. Error if out of range.
+func (m *TimeSVD) Predict(user, product int, t int64) (float64, error) {
+	if user >= m.X.Rows() || product >= m.Y.Cols() || user < 0 || product < 0 {
+		return 0.0, errors.New("User/Product index out of range")
+	}
+	return m.predict(user, product, t), nil
+}
+
+// Returns the full prediction matrix at the given time, for use with Predict and GetTopNRecommendations.
+func (m *TimeSVD) Qhat(t int64) *DenseMatrix {
+	Qhat := Zeros(m.X.Rows(), m.Y.Cols())
+	for u := 0; u < Qhat.Rows(); u++ {
+		for i := 0; i < Qhat.Cols(); i++ {
+			Qhat.Set(u, i, m.predict(u, i, t))
+		}
+	}
+	return Qhat
+}
+
+// Root mean squared error of the model on the given ratings. Users/products must be in range.
+func (m *TimeSVD) RMSE(ratings []Rating) float64 {
+	sum := float64(0)
+	for _, r := range ratings {
+		e := r.Value - m.predict(r.User, r.Product, r.Time)
+		sum += e * e
+	}
+	return math.Sqrt(sum / float64(len(ratings)))
+}
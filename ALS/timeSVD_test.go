@@ -0,0 +1,33 @@
+package ALS
+
+import (
+	"testing"
+)
+
+func TestTimeSVD(t *testing.T) {
+	day := int64(secondsPerDay)
+	// item 0 gets better ratings over time, item 1 gets worse ones.
+	ratings := make([]Rating, 0)
+	for u := 0; u < 4; u++ {
+		for d := int64(0); d < 100; d += 10 {
+			ratings = append(ratings, Rating{User: u, Product: 0, Value: 1 + 4*float64(d)/100, Time: d * day})
+			ratings = append(ratings, Rating{User: u, Product: 1, Value: 5 - 4*float64(d)/100, Time: d * day})
+		}
+	}
+	model, err := TrainTimeSVD(ratings, 2, 5, 50, 0.01, 0.01, 0.0001)
+	Assert(t, err == nil)
+	Assert(t, model.RMSE(ratings) < 1, model.RMSE(ratings))
+
+	early, _ := model.Predict(0, 0, 0)
+	late, _ := model.Predict(0, 0, 95*day)
+	Assert(t, late > early, early, late)
+	early, _ = model.Predict(0, 1, 0)
+	late, _ = model.Predict(0, 1, 95*day)
+	Assert(t, late < early, early, late)
+
+	Assert(t, model.bin(-day) == 0 && model.bin(1000*day) == 4)
+	_, err = model.Predict(4, 0, 0)
+	Assert(t, err != nil)
+	_, err = TrainTimeSVD(nil, 2, 5, 1, 0.01, 0.01, 0.0001)
+	Assert(t, err != nil)
+}
@@ -1,6 +1,7 @@
 package ALS
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strconv"
@@ -9,16 +10,6 @@ import (
 	. "github.com/skelterjohn/go.matrix"
 )
 
-func max(vals []int) int {
-	max := 0
-	for _, val := range vals {
-		if val > max {
-			max = val
-		}
-	}
-	return max
-}
-
 func min(vals []int) int {
 	min := 10000000
 	for _, val := range vals {
@@ -31,40 +22,91 @@ func min(vals []int) int {
 
 // read file with separator and load into a matrix.
 // If user/product ID's start at 1, set first product/user at row/col index 0.
-func Load(path, sep string) *DenseMatrix {
-	// read in the file
+// Error if the file can't be read, or if a line is malformed (see LoadRatings).
+func Load(path, sep string) (*DenseMatrix, error) {
+	ratings, err := LoadRatings(path, sep)
+	if err != nil {
+		return nil, err
+	}
+	// the matrix is as large as the largest user/product index
+	rows, cols := 0, 0
+	for _, rating := range ratings {
+		if rating.User < 0 || rating.Product < 0 {
+			return nil, errors.New("user/product ID's can't be negative")
+		}
+		if rating.User >= rows {
+			rows = rating.User + 1
+		}
+		if rating.Product >= cols {
+			cols = rating.Product + 1
+		}
+	}
+	mat := Zeros(rows, cols)
+	for _, rating := range ratings {
+		mat.Set(rating.User, rating.Product, rating.Value)
+	}
+	logger.Debug("ratings loaded", "path", path, "rows", mat.Rows(), "cols", mat.Cols())
+	return mat, nil
+}
+
+// A single user/product rating, along with the time it was made (the fourth column, e.g. unix seconds).
+type Rating struct {
+	User, Product int
+	Value         float64
+	Time          int64
+}
+
+// read file with separator and keep every rating, including its timestamp, instead of building a matrix.
+// Lines are expected as user, product, rating and optionally a timestamp; Time is 0 if there is none.
+// As in Load, if user/product ID's start at 1 the first product/user is set at index 0.
+// Error if the file can't be read, or if a line is malformed.
+func LoadRatings(path, sep string) ([]Rating, error) {
 	f, err := ioutil.ReadFile(path)
-	errcheck(err)
+	if err != nil {
+		return nil, err
+	}
 	lines := strings.Split(string(f), "\n")
 
-	// determine the number of rows and columns
+	ratings := make([]Rating, 0)
 	col_count := make([]int, 0)
-	row_count := make([]int, 0)
-	for _, line := range lines {
-		values := strings.Split(line, sep)
-		if line != "" {
-			row, _ := strconv.Atoi(values[0])
-			col, _ := strconv.Atoi(values[1])
-			row_count = append(row_count, row)
-			col_count = append(col_count, col)
+	for idx, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rating, err := parseRating(strings.Split(line, sep))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, idx+1, err)
+		}
+		ratings = append(ratings, rating)
+		col_count = append(col_count, rating.Product)
+	}
+	if len(ratings) > 0 && min(col_count) == 1 {
+		for i := 0; i < len(ratings); i++ {
+			ratings[i].User -= 1
+			ratings[i].Product -= 1
 		}
 	}
-	// initialize all values to 0
-	mat := Zeros(max(row_count), max(col_count))
-	// and set the values accordingly
-	for _, line := range lines {
-		values := strings.Split(line, sep)
-		fmt.Println(values)
-		if line != "" {
-			row, _ := strconv.Atoi(values[0])
-			col, _ := strconv.Atoi(values[1])
-			val, _ := strconv.ParseFloat(values[2], 64)
-			if min(col_count) == 1 {
-				mat.Set(row-1, col-1, val)
-			} else {
-				mat.Set(row, col, val)
-			}
+	return ratings, nil
+}
+
+// the fields of a line: user, product, rating and optionally a timestamp
+func parseRating(values []string) (rating Rating, err error) {
+	if len(values) < 3 {
+		return rating, errors.New("need a user, product and rating")
+	}
+	if rating.User, err = strconv.Atoi(strings.TrimSpace(values[0])); err != nil {
+		return rating, err
+	}
+	if rating.Product, err = strconv.Atoi(strings.TrimSpace(values[1])); err != nil {
+		return rating, err
+	}
+	if rating.Value, err = strconv.ParseFloat(strings.TrimSpace(values[2]), 64); err != nil {
+		return rating, err
+	}
+	if len(values) > 3 {
+		if rating.Time, err = strconv.ParseInt(strings.TrimSpace(values[3]), 10, 64); err != nil {
+			return rating, err
 		}
 	}
-	return mat
+	return rating, nil
 }
@@ -1,14 +1,39 @@
 package ALS
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 )
 
-func LoadTest(t *testing.T) {
+func TestLoad(t *testing.T) {
 	// load in the test data with the separator as a comma
-	relationship_matrix := Load("../testdata/data.txt", ",")
+	relationship_matrix, err := Load("../testdata/data.txt", ",")
 
+	Assert(t, err == nil, err)
 	Assert(t, relationship_matrix.Rows() == 4)
 	Assert(t, relationship_matrix.Cols() == 5)
 	Assert(t, relationship_matrix.Get(0, 4) == 1)
+
+	_, err = Load("../testdata/missing.txt", ",")
+	Assert(t, err != nil)
+}
+
+func TestLoadRatings(t *testing.T) {
+	ratings, err := LoadRatings("../testdata/timestamps.txt", ",")
+
+	Assert(t, err == nil, err)
+	Assert(t, len(ratings) == 6)
+	Assert(t, ratings[0].User == 0 && ratings[0].Product == 0 && ratings[0].Value == 4)
+	Assert(t, ratings[5].Time == 1433116800)
+
+	_, err = LoadRatings("../testdata/missing.txt", ",")
+	Assert(t, err != nil)
+	// malformed lines are errors, not skipped
+	for _, line := range []string{"1,2", "1,x,3", "1,2,high", "1,2,3,yesterday"} {
+		path := filepath.Join(t.TempDir(), "ratings.txt")
+		Assert(t, ioutil.WriteFile(path, []byte("1,1,4,0\n"+line+"\n"), 0644) == nil)
+		_, err = LoadRatings(path, ",")
+		Assert(t, err != nil, line)
+	}
 }
@@ -117,6 +117,7 @@ func BayesianFilter(mat *DenseMatrix, user, item int) (preds []float64, max int,
 		preds = append(preds, userProb*Prod(laplaces))
 	}
 	max = argmax(preds) + 1
+	logger.Debug("bayesian filter prediction", "user", user, "item", item, "preds", preds, "class", max)
 
 	return
 }
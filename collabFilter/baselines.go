@@ -0,0 +1,275 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// The prediction and top-N interface shared by the models of this package (UserBased, ItemBased,
+// SimilarityModel, SlopeOne, LinearItemModel, ContentBased), the baselines, and the matrix factorization
+// models of ALS through PredictedRatings.
+type Recommender interface {
+	// the predicted rating (or score) of a user for a product
+	Predict(user, item int) (float64, error)
+	// the products the user hasn't rated, and their predictions, in descending order
+	GetRecommendations(user int, products []string) ([]string, []float64, error)
+}
+
+// A non-personalized (or barely personalized) model to compare real models against, and to fall back on
+// for cold-start users.
+type Baseline struct {
+	prefs   *DenseMatrix
+	predict func(user, item int) float64
+}
+
+// Returns the baseline's prediction of a user for a product. Error if out of range.
+func (b *Baseline) Predict(user, item int) (float64, error) {
+	if user >= b.prefs.Rows() || item >= b.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	return b.predict(user, item), nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, in descending order
+// of the baseline's predictions.
+func (b *Baseline) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= b.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < b.prefs.Cols(); i++ {
+		if b.prefs.Get(user, i) == 0 {
+			scores[i] = b.predict(user, i)
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
+
+// Most popular: the score of a product is the number of users who rated it, whatever the user.
+func NewMostPopular(prefs *DenseMatrix) *Baseline {
+	prefs = replaceNA(prefs)
+	counts := make([]float64, prefs.Cols())
+	for i := range counts {
+		counts[i] = float64(len(rated(prefs.ColCopy(i))))
+	}
+	return &Baseline{prefs, func(user, item int) float64 { return counts[item] }}
+}
+
+// Trending: time-decayed popularity. Each rating counts 2^(-age / halfLife), its age being now minus
+// its time. Params: the prefs matrix, the times of the ratings (same dimensions, e.g. unix seconds), now,
+// and the half life in the same unit.
+func NewTrending(prefs, times *DenseMatrix, now, halfLife float64) (*Baseline, error) {
+	if prefs.Rows() != times.Rows() || prefs.Cols() != times.Cols() {
+		return nil, errors.New("prefs and times need the same dimensions")
+	}
+	if halfLife <= 0 {
+		return nil, errors.New("half life must be positive")
+	}
+	prefs = replaceNA(prefs)
+	scores := make([]float64, prefs.Cols())
+	for u := 0; u < prefs.Rows(); u++ {
+		for i := 0; i < prefs.Cols(); i++ {
+			if prefs.Get(u, i) != 0 {
+				scores[i] += math.Pow(2, -(now-times.Get(u, i))/halfLife)
+			}
+		}
+	}
+	return &Baseline{prefs, func(user, item int) float64 { return scores[item] }}, nil
+}
+
+// Random: predictions are drawn uniformly between the lowest and highest rating in prefs, so
+// recommendations come in a random order. Not safe for concurrent use.
+func NewRandom(prefs *DenseMatrix, seed int64) *Baseline {
+	prefs = replaceNA(prefs)
+	ratings := rated(prefs.Array())
+	low, high := float64(0), float64(0)
+	if len(ratings) > 0 {
+		low, high = ratings[0], ratings[0]
+	}
+	for _, rating := range ratings {
+		low, high = math.Min(low, rating), math.Max(high, rating)
+	}
+	r := rand.New(rand.NewSource(seed))
+	return &Baseline{prefs, func(user, item int) float64 { return low + (high-low)*r.Float64() }}
+}
+
+// Global mean: every prediction is the mean of all the ratings.
+func NewGlobalMean(prefs *DenseMatrix) *Baseline {
+	prefs = replaceNA(prefs)
+	global := mean(rated(prefs.Array()))
+	return &Baseline{prefs, func(user, item int) float64 { return global }}
+}
+
+// the means of ratings, and the global mean for those without any
+func meansOr(means []float64, x func(idx int) []float64, global float64) []float64 {
+	for idx := range means {
+		if len(rated(x(idx))) == 0 {
+			means[idx] = global
+		}
+	}
+	return means
+}
+
+// User mean: predicts the mean rating of the user (the global mean if they have none).
+func NewUserMean(prefs *DenseMatrix) *Baseline {
+	prefs = replaceNA(prefs)
+	means := meansOr(UserMeans(prefs), prefs.RowCopy, mean(rated(prefs.Array())))
+	return &Baseline{prefs, func(user, item int) float64 { return means[user] }}
+}
+
+// Item mean: predicts the mean rating of the product (the global mean if it has none).
+func NewItemMean(prefs *DenseMatrix) *Baseline {
+	prefs = replaceNA(prefs)
+	means := meansOr(ItemMeans(prefs), prefs.ColCopy, mean(rated(prefs.Array())))
+	return &Baseline{prefs, func(user, item int) float64 { return means[item] }}
+}
+
+// Tries each model in turn: the first successful prediction, and the first non-empty recommendations, are
+// returned. Typically a personalized model followed by a baseline for cold-start users, whom the
+// personalized model can't make predictions for.
+type Fallback []Recommender
+
+func (f Fallback) Predict(user, item int) (float64, error) {
+	err := errors.New("no models to predict with")
+	for _, model := range f {
+		var pred float64
+		if pred, err = model.Predict(user, item); err == nil {
+			return pred, nil
+		}
+	}
+	return 0, err
+}
+
+func (f Fallback) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	err := errors.New("no models to recommend with")
+	for _, model := range f {
+		var prods []string
+		var vals []float64
+		if prods, vals, err = model.GetRecommendations(user, products); err == nil && len(prods) > 0 {
+			return prods, vals, nil
+		}
+	}
+	return nil, nil, err
+}
+
+// Weighted blend of models, e.g. collaborative filtering with ContentBased so that new products can come up
+// too. The prediction is sum_m w_m * p_m / sum_m w_m over the models that can make one, so the models' scores
+// should be on comparable scales (or the weights should make them so).
+type Blend struct {
+	Models  []Recommender
+	Weights []float64
+
+	prefs *DenseMatrix
+}
+
+// Params: the prefs matrix the models were built from, the models and their weights.
+// Error if there isn't a weight for every model.
+func NewBlend(prefs *DenseMatrix, models []Recommender, weights []float64) (*Blend, error) {
+	if len(models) != len(weights) {
+		return nil, errors.New("need a weight for every model")
+	}
+	return &Blend{models, weights, replaceNA(prefs)}, nil
+}
+
+func (b *Blend) Predict(user, item int) (float64, error) {
+	if user >= b.prefs.Rows() || item >= b.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	pred, weights := float64(0), float64(0)
+	for idx, model := range b.Models {
+		if p, err := model.Predict(user, item); err == nil {
+			pred += b.Weights[idx] * p
+			weights += b.Weights[idx]
+		}
+	}
+	if weights == 0 {
+		return 0, errors.New("no model could predict")
+	}
+	return pred / weights, nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, in descending order
+// of the blended predictions.
+func (b *Blend) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= b.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < b.prefs.Cols(); i++ {
+		if b.prefs.Get(user, i) == 0 {
+			if pred, err := b.Predict(user, i); err == nil {
+				scores[i] = pred
+			}
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
+
+// User-based collaborative filtering, as GetRecommendationsWithOptions and Predict, as a Recommender.
+type UserBased struct {
+	Options Options
+
+	prefs *DenseMatrix
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing) and the neighborhood options.
+func NewUserBased(prefs *DenseMatrix, opts Options) *UserBased {
+	return &UserBased{opts, replaceNA(prefs)}
+}
+
+// Returns the predicted rating of a user for a product. Error if out of range, or if no neighbor rated it.
+func (m *UserBased) Predict(user, item int) (float64, error) {
+	pred, err := Predict(m.prefs, user, item, m.Options)
+	return pred.Rating, err
+}
+
+func (m *UserBased) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	return GetRecommendationsWithOptions(m.prefs, user, products, m.Options)
+}
+
+// A matrix of predicted ratings (users x products) as a Recommender, such as the Qhat of ALS.TrainEALS,
+// ALS.TrainHybrid, ALS.TrainTimeSVD or ALS.TensorModel, so that they can be blended with (or fall back on)
+// the models of this package.
+type PredictedRatings struct {
+	Qhat *DenseMatrix
+
+	prefs *DenseMatrix
+}
+
+// Params: the prefs matrix the model was trained on, whose rated products aren't recommended again, and the
+// predicted ratings. Error if they don't have the same dimensions.
+func NewPredictedRatings(prefs, Qhat *DenseMatrix) (*PredictedRatings, error) {
+	if prefs.Rows() != Qhat.Rows() || prefs.Cols() != Qhat.Cols() {
+		return nil, errors.New("prefs and Qhat need the same dimensions")
+	}
+	return &PredictedRatings{Qhat, replaceNA(prefs)}, nil
+}
+
+// Returns the predicted rating of a user for a product. Error if out of range.
+func (m *PredictedRatings) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	return m.Qhat.Get(user, item), nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, in descending order
+// of the predicted ratings.
+func (m *PredictedRatings) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			scores[i] = m.Qhat.Get(user, i)
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
@@ -0,0 +1,148 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// the models share the Recommender interface
+var _ = []Recommender{&UserBased{}, &PredictedRatings{}, &ItemBased{}, &SimilarityModel{}, &SlopeOne{}, &LinearItemModel{}, &ContentBased{}, &Baseline{}, Fallback{}, &Blend{}}
+
+func TestBaselines(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		5, 3, 0, 1,
+		4, 0, 0, 1,
+		1, 1, 0, 5,
+		0, 0, 0, 0}, 4, 4)
+
+	prods, scores, err := NewMostPopular(prefs).GetRecommendations(1, nil)
+	Assert(t, err == nil && len(prods) == 2 && prods[0] == "1" && scores[0] == 2 && scores[1] == 0, prods, scores)
+
+	pred, _ := NewGlobalMean(prefs).Predict(3, 2)
+	Assert(t, pred == 21.0/8, pred)
+	pred, _ = NewUserMean(prefs).Predict(2, 0)
+	Assert(t, math.Abs(pred-7.0/3) < 1e-9, pred)
+	// no ratings, so the global mean
+	pred, _ = NewUserMean(prefs).Predict(3, 0)
+	Assert(t, pred == 21.0/8, pred)
+	pred, _ = NewItemMean(prefs).Predict(3, 0)
+	Assert(t, math.Abs(pred-10.0/3) < 1e-9, pred)
+	prods, _, _ = NewItemMean(prefs).GetRecommendations(3, nil)
+	Assert(t, prods[0] == "0", prods)
+
+	random := NewRandom(prefs, 47)
+	for i := 0; i < 4; i++ {
+		pred, err = random.Predict(0, i)
+		Assert(t, err == nil && pred >= 1 && pred <= 5, pred)
+	}
+	prods, _, _ = NewRandom(prefs, 47).GetRecommendations(3, nil)
+	Assert(t, len(prods) == 4)
+
+	_, err = NewGlobalMean(prefs).Predict(4, 0)
+	Assert(t, err != nil)
+}
+
+func TestTrending(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		1, 1,
+		1, 0,
+		0, 1}, 3, 2)
+	// product 0 was popular long ago, product 1 is popular now
+	times := MakeRatingMatrix([]float64{
+		0, 100,
+		0, 0,
+		0, 90}, 3, 2)
+	model, err := NewTrending(prefs, times, 100, 10)
+	Assert(t, err == nil)
+	pred, _ := model.Predict(0, 1)
+	Assert(t, pred == 1.5, pred)
+	prods, _, _ := model.GetRecommendations(1, nil)
+	Assert(t, len(prods) == 1 && prods[0] == "1")
+
+	_, err = NewTrending(prefs, MakeRatingMatrix([]float64{0}, 1, 1), 100, 10)
+	Assert(t, err != nil)
+}
+
+func TestFallback(t *testing.T) {
+	prefs := testPrefs()
+	for i := 0; i < 5; i++ {
+		prefs.Set(1, i, 0)
+	}
+	model := Fallback{NewItemBased(prefs, 2, CosineSim), NewMostPopular(prefs)}
+	// user 1 has no ratings, so the item-based model can't predict
+	_, err := model[0].Predict(1, 0)
+	Assert(t, err != nil)
+	pred, err := model.Predict(1, 0)
+	Assert(t, err == nil && pred == 4, pred)
+	prods, _, err := model.GetRecommendations(1, nil)
+	Assert(t, err == nil && len(prods) == 5, prods)
+	// others get the item-based predictions
+	prods, _, _ = model.GetRecommendations(4, nil)
+	expected, _, _ := model[0].GetRecommendations(4, nil)
+	Assert(t, len(prods) == len(expected) && prods[0] == expected[0])
+
+	_, err = Fallback{}.Predict(0, 0)
+	Assert(t, err != nil)
+}
+
+func TestUserBased(t *testing.T) {
+	prefs := testPrefs()
+	opts := Options{K: 2, Normalization: MeanCentering}
+	model := NewUserBased(prefs, opts)
+	prods, scores, err := model.GetRecommendations(1, nil)
+	expected, expected_scores, _ := GetRecommendationsWithOptions(prefs, 1, nil, opts)
+	Assert(t, err == nil && len(prods) == len(expected) && prods[0] == expected[0] && scores[0] == expected_scores[0], prods)
+	pred, err := model.Predict(1, 4)
+	expected_pred, _ := Predict(prefs, 1, 4, opts)
+	Assert(t, err == nil && pred == expected_pred.Rating, pred)
+	_, err = model.Predict(5, 0)
+	Assert(t, err != nil)
+}
+
+func TestPredictedRatings(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		5, 0, 0,
+		0, 3, 0}, 2, 3)
+	Qhat := MakeRatingMatrix([]float64{
+		4.8, 2, 3.5,
+		1, 3.1, 4}, 2, 3)
+	model, err := NewPredictedRatings(prefs, Qhat)
+	Assert(t, err == nil)
+	pred, err := model.Predict(1, 2)
+	Assert(t, err == nil && pred == 4, pred)
+	// only the products the user hasn't rated
+	prods, scores, err := model.GetRecommendations(0, []string{"a", "b", "c"})
+	Assert(t, err == nil && len(prods) == 2 && prods[0] == "c" && scores[0] == 3.5, prods, scores)
+	_, err = model.Predict(2, 0)
+	Assert(t, err != nil)
+
+	_, err = NewPredictedRatings(prefs, MakeRatingMatrix([]float64{1}, 1, 1))
+	Assert(t, err != nil)
+}
+
+func TestRecommendersOwnCopy(t *testing.T) {
+	builders := []func(prefs *DenseMatrix) Recommender{
+		func(prefs *DenseMatrix) Recommender { return NewMostPopular(prefs) },
+		func(prefs *DenseMatrix) Recommender { return NewGlobalMean(prefs) },
+		func(prefs *DenseMatrix) Recommender { return NewUserMean(prefs) },
+		func(prefs *DenseMatrix) Recommender { return NewItemMean(prefs) },
+		func(prefs *DenseMatrix) Recommender {
+			model, _ := NewTrending(prefs, testPrefs(), 5, 1)
+			return model
+		},
+		func(prefs *DenseMatrix) Recommender {
+			model, _ := NewBlend(prefs, []Recommender{NewItemMean(prefs)}, []float64{1})
+			return model
+		},
+		func(prefs *DenseMatrix) Recommender { return NewUserBased(prefs, Options{K: 2}) },
+		func(prefs *DenseMatrix) Recommender {
+			model, _ := NewPredictedRatings(prefs, testPrefs())
+			return model
+		},
+	}
+	for _, build := range builders {
+		assertOwnCopy(t, build)
+	}
+}
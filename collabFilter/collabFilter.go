@@ -3,17 +3,17 @@ package collabFilter
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"sort"
-	"strconv"
 
 	. "github.com/skelterjohn/go.matrix"
 )
 
+// logs the errors that can only come from a bug, such as mismatched dimensions inside a model. Errors a
+// caller can cause (files, out of range indices...) are returned instead.
 func errcheck(err error) {
 	if err != nil {
-		fmt.Printf("\nError:  %v occured", err)
+		logger.Error("collabFilter error", "err", err)
 	}
 }
 
@@ -39,34 +39,51 @@ func NormSquared(a []float64) float64 {
 }
 
 // Cosine Similarity between two vectors
-// Returns cos similarity on a scale from 0 to 1.
+// Returns cos similarity on a scale from 0 to 1, or NaN if the vectors have different lengths.
 func CosineSim(a, b []float64) float64 {
-	dp, err := DotProduct(a, b)
-	errcheck(err)
+	dp := dotOrNaN(a, b)
 	a_squared := NormSquared(a)
 	b_sqaured := NormSquared(b)
 	return dp / (a_squared * b_sqaured)
 }
 
+// a.b, or NaN if a and b have different lengths, so that the similarities built on it are NaN (undefined)
+// rather than a made up value. Similarities can't return an error.
+func dotOrNaN(a, b []float64) float64 {
+	dp, err := DotProduct(a, b)
+	if err != nil {
+		return math.NaN()
+	}
+	return dp
+}
+
 // defined as A n B / A u B. Used for binary user/product matrices.
+// A and B are the products each user has (non-zero entries), so products neither has don't count.
+// Returns 0 if neither has any product.
 func Jaccard(a, b []float64) float64 {
 	intersection := float64(0)
-	for i := 0; i < len(a); i++ {
-		if a[i] == b[i] {
+	union := float64(0)
+	for i := 0; i < len(a) && i < len(b); i++ {
+		has_a := a[i] != 0 && !math.IsNaN(a[i])
+		has_b := b[i] != 0 && !math.IsNaN(b[i])
+		if has_a && has_b {
 			intersection += 1
 		}
-	}
-	union := float64(0)
-	for i := 0; i < len(a); i++ {
-		if a[i] > 0 || b[i] > 0 {
+		if has_a || has_b {
 			union += 1
 		}
 	}
+	if union == 0 {
+		return 0
+	}
 	return intersection / union
 }
 
+// a copy of prefs with the missing (NaN) entries set to 0. Always a new matrix, so that the models don't
+// change the caller's matrix, or see the caller's later changes to it.
 func replaceNA(prefs *DenseMatrix) *DenseMatrix {
-	arr := prefs.Array()
+	arr := make([]float64, prefs.Rows()*prefs.Cols())
+	copy(arr, prefs.Array())
 	for i := 0; i < len(arr); i++ {
 		if math.IsNaN(arr[i]) {
 			arr[i] = float64(0)
@@ -75,39 +92,239 @@ func replaceNA(prefs *DenseMatrix) *DenseMatrix {
 	return MakeDenseMatrix(arr, prefs.Rows(), prefs.Cols())
 }
 
-// Gets Recommendations for a user (row index) based on the prefs matrix.
-// Uses cosine similarity for rating scale, and jaccard similarity if binary
+// Gets Recommendations for a user (row index) based on the prefs matrix: each unrated product is predicted
+// by the cosine similarity weighted mean of the ratings of every other user who rated it. Same as
+// GetRecommendationsWithOptions with Options{}; set Options.Similarity there to compare users differently.
+// Use GetBinaryRecommendations for binary matrices.
 func GetRecommendations(prefs *DenseMatrix, user int, products []string) ([]string, []float64, error) {
-	// make sure user is in the preference matrix
-	if user >= prefs.Rows() {
-		return nil, nil, errors.New("user index out of range")
+	return GetRecommendationsWithOptions(prefs, user, products, Options{})
+}
+
+// How the neighbors' ratings are combined into a prediction.
+type Normalization int
+
+const (
+	// similarity weighted mean of the neighbors' raw ratings, as in GetRecommendations.
+	NoNormalization Normalization = iota
+	// Resnick's formula: the user's mean rating plus the similarity weighted mean of each
+	// neighbor's deviation from their own mean rating.
+	MeanCentering
+	// like MeanCentering, but deviations are divided by each neighbor's standard deviation (z-scores),
+	// and the weighted mean is scaled back by the user's standard deviation.
+	ZScore
+)
+
+// Options for the neighborhood of GetRecommendationsWithOptions.
+type Options struct {
+	// the number of most similar users who rated an item that its prediction is made from. 0 uses all of them.
+	K int
+	// if set, users with a similarity below *MinSimilarity are never used as neighbors. If nil, every
+	// similarity is used, negative ones too: users who disagree with the user count against what they
+	// liked, which makes most sense with MeanCentering or ZScore.
+	MinSimilarity *float64
+	// how users are compared, e.g. Pearson, Spearman, AdjustedCosine(ItemMeans(prefs)) or any registered
+	// similarity. Defaults to CosineSim, or Jaccard for GetBinaryRecommendationsWithOptions.
+	Similarity Similarity
+	// how neighbors' ratings are normalized before they are weighed. Defaults to NoNormalization.
+	Normalization Normalization
+	// if set, the user is only compared with these candidates (e.g. from a MinHash or SimHash index)
+	// instead of with every other user.
+	Candidates CandidateSource
+}
+
+// the users to compare user with: the candidates given by opts, or everybody else.
+func candidateUsers(prefs *DenseMatrix, user int, opts Options) []int {
+	if opts.Candidates != nil {
+		return opts.Candidates.Candidates(user)
 	}
-	prefs = replaceNA(prefs)
-	// item ratings
-	ratings := make(map[int]float64, 0)
-	sims := make(map[int]float64, 0)
-	// Get user row from prefs matrix
-	user_ratings := prefs.GetRowVector(user).Array()
+	others := make([]int, 0, prefs.Rows()-1)
 	for i := 0; i < prefs.Rows(); i++ {
-		// don't compare row to itself.
 		if i != user {
-			// get cosine similarity for other scores.
-			other := prefs.GetRowVector(i).Array()
-			cos_sim := CosineSim(user_ratings, other)
-			// get product recs for neighbors
-			for idx, val := range other {
-				if (user_ratings[idx] == 0 || math.IsNaN(user_ratings[idx])) && val != 0 {
-					weighted_rating := val * cos_sim
-					ratings[idx] += weighted_rating
-					sims[idx] += cos_sim
-				}
+			others = append(others, i)
+		}
+	}
+	return others
+}
+
+// a user's rating of a product, 0 if missing (0 or NaN)
+func ratingOf(prefs *DenseMatrix, user, item int) float64 {
+	rating := prefs.Get(user, item)
+	if math.IsNaN(rating) {
+		return 0
+	}
+	return rating
+}
+
+// a user's ratings (row), with the missing (NaN) ones set to 0
+func userRatings(prefs *DenseMatrix, user int) []float64 {
+	ratings := prefs.RowCopy(user)
+	for i, val := range ratings {
+		if math.IsNaN(val) {
+			ratings[i] = 0
+		}
+	}
+	return ratings
+}
+
+// the mean and standard deviation of the ratings of some users
+type ratingStats struct {
+	means, stds map[int]float64
+}
+
+func newRatingStats(prefs *DenseMatrix, users []int) ratingStats {
+	stats := ratingStats{make(map[int]float64, len(users)), make(map[int]float64, len(users))}
+	for _, u := range users {
+		ratings := rated(prefs.RowCopy(u))
+		stats.means[u] = mean(ratings)
+		for _, val := range ratings {
+			stats.stds[u] += (val - stats.means[u]) * (val - stats.means[u])
+		}
+		if len(ratings) > 0 {
+			stats.stds[u] = math.Sqrt(stats.stds[u] / float64(len(ratings)))
+		}
+	}
+	return stats
+}
+
+// the stats that opts.Normalization needs: those of the user and of the users compared with, or none.
+func neighborhoodStats(prefs *DenseMatrix, user int, sims []Neighbor, opts Options) ratingStats {
+	if opts.Normalization == NoNormalization {
+		return ratingStats{}
+	}
+	users := []int{user}
+	for _, n := range sims {
+		users = append(users, n.Index)
+	}
+	return newRatingStats(prefs, users)
+}
+
+// similarities of user with the users given by candidateUsers, leaving out the user themselves and
+// unknown users. Only these users are ever looked at.
+func userSimilarities(prefs *DenseMatrix, user int, opts Options) []Neighbor {
+	similarity := opts.Similarity
+	if similarity == nil {
+		similarity = CosineSim
+	}
+	user_ratings := userRatings(prefs, user)
+	sims := make([]Neighbor, 0)
+	for _, i := range candidateUsers(prefs, user, opts) {
+		if i == user || i < 0 || i >= prefs.Rows() {
+			continue
+		}
+		sim := similarity(user_ratings, userRatings(prefs, i))
+		if math.IsNaN(sim) {
+			sim = 0
+		}
+		sims = append(sims, Neighbor{i, sim})
+	}
+	return sims
+}
+
+// the neighbors used to predict a user's rating of item: the K most similar users who rated it,
+// leaving out those below the minimum similarity if there is one. Most similar first.
+func itemNeighbors(prefs *DenseMatrix, item int, sims []Neighbor, opts Options) []Neighbor {
+	neighbors := make([]Neighbor, 0)
+	for _, n := range sims {
+		if ratingOf(prefs, n.Index, item) != 0 && (opts.MinSimilarity == nil || n.Similarity >= *opts.MinSimilarity) {
+			neighbors = append(neighbors, n)
+		}
+	}
+	sort.Sort(bySimilarity(neighbors))
+	if opts.K > 0 && opts.K < len(neighbors) {
+		neighbors = neighbors[:opts.K]
+	}
+	return neighbors
+}
+
+// the rating of user for item, normalized according to opts
+func normalizedRating(prefs *DenseMatrix, user, item int, opts Options, stats ratingStats) float64 {
+	rating := ratingOf(prefs, user, item)
+	switch opts.Normalization {
+	case MeanCentering:
+		rating -= stats.means[user]
+	case ZScore:
+		rating -= stats.means[user]
+		if stats.stds[user] > 0 {
+			rating /= stats.stds[user]
+		}
+	}
+	return rating
+}
+
+// similarity weighted mean of the neighbors' ratings of item, normalized according to opts. Divided by
+// sum(|s|), so that negative similarities don't cancel out the positive ones. ok is false if there is
+// nothing to weigh.
+func weightedMean(prefs *DenseMatrix, user, item int, neighbors []Neighbor, opts Options, stats ratingStats) (pred float64, ok bool) {
+	ratings, sims := float64(0), float64(0)
+	for _, n := range neighbors {
+		ratings += n.Similarity * normalizedRating(prefs, n.Index, item, opts, stats)
+		sims += math.Abs(n.Similarity)
+	}
+	if sims == 0 {
+		return 0, false
+	}
+	switch opts.Normalization {
+	case MeanCentering:
+		return stats.means[user] + ratings/sims, true
+	case ZScore:
+		return stats.means[user] + stats.stds[user]*ratings/sims, true
+	}
+	return ratings / sims, true
+}
+
+// Gets Recommendations for a user (row index) based on the prefs matrix, like GetRecommendations, but
+// each unrated product is predicted only from the neighborhood given by opts: the K most similar users
+// who actually rated it, with a similarity of at least *opts.MinSimilarity if it is set.
+// Users are compared with opts.Similarity, and their ratings are normalized according to opts.Normalization.
+// If opts.Candidates is set, only the candidates are considered as neighbors.
+func GetRecommendationsWithOptions(prefs *DenseMatrix, user int, products []string, opts Options) ([]string, []float64, error) {
+	// make sure user is in the preference matrix
+	if user >= prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	sims := userSimilarities(prefs, user, opts)
+	stats := neighborhoodStats(prefs, user, sims, opts)
+	user_ratings := userRatings(prefs, user)
+	scores := make(map[int]float64)
+	for idx := 0; idx < prefs.Cols(); idx++ {
+		if user_ratings[idx] == 0 {
+			if pred, ok := weightedMean(prefs, user, idx, itemNeighbors(prefs, idx, sims, opts), opts, stats); ok {
+				scores[idx] = pred
 			}
 		}
 	}
-	recs, vals := calculateWeightedMean(ratings, sims, products)
+	recs, vals := rank(scores, products)
 	return recs, vals, nil
 }
 
+// A predicted rating, the number of neighbors it was computed from, and how much to trust it.
+type Prediction struct {
+	Rating    float64
+	Neighbors int
+	// sum(|s|) / (sum(|s|) + 1) over the similarities s of the neighbors used: 0 without neighbors,
+	// and closer to 1 with more, and more similar, neighbors.
+	Confidence float64
+}
+
+// Predicts a user's (row index) rating of a product (column index) from the neighborhood given by opts,
+// the same way GetRecommendationsWithOptions does. Error if out of range, or if no neighbor rated the product.
+func Predict(prefs *DenseMatrix, user, item int, opts Options) (Prediction, error) {
+	if user >= prefs.Rows() || item >= prefs.Cols() || user < 0 || item < 0 {
+		return Prediction{}, errors.New("user/product index out of range")
+	}
+	sims := userSimilarities(prefs, user, opts)
+	neighbors := itemNeighbors(prefs, item, sims, opts)
+	pred, ok := weightedMean(prefs, user, item, neighbors, opts, neighborhoodStats(prefs, user, neighbors, opts))
+	if !ok {
+		return Prediction{}, errors.New("no neighbors to predict from")
+	}
+	total := float64(0)
+	for _, n := range neighbors {
+		total += math.Abs(n.Similarity)
+	}
+	return Prediction{Rating: pred, Neighbors: len(neighbors), Confidence: total / (total + 1)}, nil
+}
+
 func sum(x []float64) float64 {
 	sum := float64(0)
 	for i := 0; i < len(x); i++ {
@@ -119,59 +336,50 @@ func sum(x []float64) float64 {
 // Gets Recommendations for a user (row index) based on the prefs matrix.
 // Uses cosine similarity for rating scale, and jaccard similarity if binary
 func GetBinaryRecommendations(prefs *DenseMatrix, user int, products []string) ([]string, []float64, error) {
+	return GetBinaryRecommendationsWithOptions(prefs, user, products, Options{})
+}
+
+// Same as GetBinaryRecommendations, but users are compared with opts.Similarity (Jaccard if nil),
+// and only with opts.Candidates if it is set.
+func GetBinaryRecommendationsWithOptions(prefs *DenseMatrix, user int, products []string, opts Options) ([]string, []float64, error) {
 	// make sure user is in the preference matrix
-	if user >= prefs.Rows() {
+	if user >= prefs.Rows() || user < 0 {
 		return nil, nil, errors.New("user index out of range")
 	}
+	similarity := opts.Similarity
+	if similarity == nil {
+		similarity = Jaccard
+	}
 	prefs = replaceNA(prefs)
 	// item ratings
-	ratings := make(map[float64]string)
+	ratings := make(map[int]float64)
 	// Get user row from prefs matrix
 	user_ratings := prefs.GetRowVector(user).Array()
+	candidates := candidateUsers(prefs, user, opts)
 
 	for ii := 0; ii < prefs.Cols(); ii++ {
 		if user_ratings[ii] == float64(0) {
-			num_liked := sum(prefs.GetColVector(ii).Array())
-			num_disliked := float64(prefs.Rows()) - num_liked
 			jaccard_liked := make([]float64, 0)
 			jaccard_disliked := make([]float64, 0)
-			for i := 0; i < prefs.Rows(); i++ {
-				if i != user {
+			for _, i := range candidates {
+				if i != user && i >= 0 && i < prefs.Rows() {
 					other := prefs.GetRowVector(i).Array()
 					if other[ii] == float64(0) {
-						jaccard_disliked = append(jaccard_disliked, Jaccard(user_ratings, other))
+						jaccard_disliked = append(jaccard_disliked, similarity(user_ratings, other))
 					} else {
-						jaccard_liked = append(jaccard_liked, Jaccard(user_ratings, other))
+						jaccard_liked = append(jaccard_liked, similarity(user_ratings, other))
 					}
 				}
 			}
-			rating := (sum(jaccard_liked) - sum(jaccard_disliked)) / (num_disliked + num_liked)
-			if products != nil {
-				ratings[rating] = products[ii]
-			} else {
-				ratings[rating] = strconv.Itoa(ii)
-			}
+			// the user and all the users compared with
+			num_users := float64(len(jaccard_liked) + len(jaccard_disliked) + 1)
+			ratings[ii] = (sum(jaccard_liked) - sum(jaccard_disliked)) / num_users
 		}
 	}
-	prods, scores := sortMap(ratings)
+	prods, scores := rank(ratings, products)
 	return prods, scores, nil
 }
 
-func calculateWeightedMean(ratings, sims map[int]float64, products []string) (recommends []string, values []float64) {
-	recommendations := make(map[float64]string, 0)
-	for k, v := range ratings {
-		mean_product_rating := v / sims[k]
-		fmt.Println(mean_product_rating)
-		if products != nil {
-			recommendations[mean_product_rating] = products[k]
-		} else {
-			recommendations[mean_product_rating] = strconv.Itoa(k)
-		}
-	}
-	recommends, values = sortMap(recommendations)
-	return
-}
-
 // Sorts a map of floats -> strings to get best recommendations. Probably a better way to do this.
 func sortMap(recs map[float64]string) ([]string, []float64) {
 	vals := make([]float64, 0)
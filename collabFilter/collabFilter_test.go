@@ -2,6 +2,9 @@ package collabFilter
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -11,6 +14,17 @@ func Assert(t *testing.T, condition bool, args ...interface{}) {
 	}
 }
 
+func TestLoad(t *testing.T) {
+	prefs, err := Load("../testdata/data.txt", ",")
+	Assert(t, err == nil, err)
+	Assert(t, prefs.Rows() == 4 && prefs.Cols() == 5 && prefs.Get(0, 0) == 4, prefs)
+
+	_, err = Load("../testdata/missing.txt", ",")
+	Assert(t, err != nil)
+	_, err = Load("../testdata/data.txt", "\t")
+	Assert(t, err != nil)
+}
+
 func TestSortMap(t *testing.T) {
 	to_sort := make(map[float64]string, 0)
 	to_sort[0.23423] = "Drew"
@@ -37,6 +51,16 @@ func TestJaccard(t *testing.T) {
 	Assert(t, jaccard_sim == 0.6)
 }
 
+func TestJaccardIgnoresSharedAbsences(t *testing.T) {
+	// one product in common, out of 2, and 8 products neither has
+	x := []float64{1, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	y := []float64{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	Assert(t, Jaccard(x, y) == 0.5, Jaccard(x, y))
+	// nothing in common
+	Assert(t, Jaccard([]float64{1, 0, 0}, []float64{0, 1, 0}) == 0)
+	Assert(t, Jaccard([]float64{0, 0}, []float64{0, 0}) == 0)
+}
+
 func TestRatingRecommendations(t *testing.T) {
 	// User product matrix. 0 or math.NaN indicates products not viewed by user.
 	// Uses cosine similarity.
@@ -73,6 +97,148 @@ func TestBinaryRecommendations(t *testing.T) {
 
 	Assert(t, err == nil)
 	Assert(t, prods[0] == "Spiderman", prods[1] == "Pacific Rim")
-	Assert(t, scores[0] > 0.4, scores[1] < 0.3)
+	// Spiderman is liked by every other user: (0.4 + 0.6 + 0.5 + 0.4) / 5
+	Assert(t, math.Abs(scores[0]-0.38) < 1e-9, scores[1] < 0.3)
+
+	// products with the same score are all kept
+	prods, scores, err = GetBinaryRecommendations(MakeRatingMatrix([]float64{
+		1, 1, 0,
+		0, 0, 0}, 2, 3), 1, nil)
+	Assert(t, err == nil && len(prods) == 3 && scores[0] == scores[2], prods, scores)
+	_, _, err = GetBinaryRecommendations(binaryPrefs, -1, products)
+	Assert(t, err != nil)
+}
+
+func TestNeighborhoodRecommendations(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		2, 3, 4, 1, 5,
+		3, 0, 3, 3, 0,
+		4, 4, 1, 2, 3,
+		2, 4, 0, 3, 4,
+		3, 1, 3, 0, 4}, 5, 5)
+	products := []string{"Spiderman", "Big Momma's House", "Vanilla Sky", "Pacific Rim", "The Mask"}
+
+	// using every neighbor gives the same ranking as GetRecommendations
+	prods, scores, err := GetRecommendationsWithOptions(prefs, 1, products, Options{})
+	Assert(t, err == nil)
+	Assert(t, prods[0] == "The Mask" && prods[1] == "Big Momma's House", prods)
+	Assert(t, scores[0] > 3.80, scores)
+	default_prods, default_scores, _ := GetRecommendations(prefs, 1, products)
+	Assert(t, reflect.DeepEqual(prods, default_prods) && reflect.DeepEqual(scores, default_scores), default_prods, default_scores)
+
+	// with a single neighbor, each product gets the rating of the most similar user who rated it.
+	sims := userSimilarities(prefs, 1, Options{})
+	Assert(t, len(sims) == 4, sims)
+	neighbors := itemNeighbors(prefs, 4, sims, Options{K: 1})
+	Assert(t, len(neighbors) == 1, neighbors)
+	for _, n := range sims {
+		Assert(t, n.Index != 1 && (prefs.Get(n.Index, 4) == 0 || n.Similarity <= neighbors[0].Similarity), sims)
+	}
+	prods, scores, _ = GetRecommendationsWithOptions(prefs, 1, nil, Options{K: 1})
+	for idx, prod := range prods {
+		if prod == "4" {
+			Assert(t, scores[idx] == prefs.Get(neighbors[0].Index, 4), scores)
+		}
+	}
+
+	// nobody is similar enough
+	too_similar := 1.1
+	prods, _, err = GetRecommendationsWithOptions(prefs, 1, products, Options{MinSimilarity: &too_similar})
+	Assert(t, err == nil && len(prods) == 0, prods)
+	_, _, err = GetRecommendationsWithOptions(prefs, 5, products, Options{})
+	Assert(t, err != nil)
+}
+
+func TestNormalizedRecommendations(t *testing.T) {
+	// user 0 is a harsh rater, user 1 a generous one. Both agree with user 2 on what's better.
+	prefs := MakeRatingMatrix([]float64{
+		1, 2, 1, 4,
+		3, 4, 3, 6,
+		2, 3, 2, 0}, 3, 4)
+
+	_, raw, _ := GetRecommendationsWithOptions(prefs, 2, nil, Options{})
+	prods, centered, err := GetRecommendationsWithOptions(prefs, 2, nil, Options{Normalization: MeanCentering})
+	Assert(t, err == nil && prods[0] == "3", prods)
+	// user 2's mean is 7/3, and both neighbors rate product 3 2 above their own mean.
+	Assert(t, math.Abs(centered[0]-(7.0/3+2)) < 1e-9, centered)
+	// the raw mean is pulled towards the generous rater, who is the more similar one
+	Assert(t, raw[0] > centered[0], raw, centered)
+
+	_, zscored, err := GetRecommendationsWithOptions(prefs, 2, nil, Options{Normalization: ZScore})
+	Assert(t, err == nil && zscored[0] > 7.0/3, zscored)
+
+	stats := newRatingStats(prefs, []int{0, 2})
+	Assert(t, stats.means[0] == 2 && math.Abs(stats.stds[2]-math.Sqrt(2.0/9)) < 1e-9, stats)
+	// only the stats of the user and their neighborhood, and only if they are needed
+	sims := userSimilarities(prefs, 2, Options{})
+	Assert(t, len(neighborhoodStats(prefs, 2, sims, Options{}).means) == 0)
+	Assert(t, len(neighborhoodStats(prefs, 2, sims[:1], Options{Normalization: ZScore}).means) == 2)
+}
+
+func TestNegativeNeighbors(t *testing.T) {
+	// users 0 and 1 always disagree, and user 0 disliked product 3
+	prefs := MakeRatingMatrix([]float64{
+		5, 1, 5, 1,
+		1, 5, 1, 0}, 2, 4)
+	opts := Options{Similarity: Pearson, Normalization: MeanCentering}
+	prods, scores, err := GetRecommendationsWithOptions(prefs, 1, nil, opts)
+	Assert(t, err == nil && len(prods) == 1 && prods[0] == "3", prods)
+	// user 1's mean is 7/3, and user 0 rated product 3 2 below their own mean
+	Assert(t, math.Abs(scores[0]-(7.0/3+2)) < 1e-9, scores)
+
+	// unless they are left out
+	positive := float64(0)
+	opts.MinSimilarity = &positive
+	prods, _, err = GetRecommendationsWithOptions(prefs, 1, nil, opts)
+	Assert(t, err == nil && len(prods) == 0, prods)
+
+	// raw ratings are weighed by sum(|s|), not by the signed sum, which is 0 here
+	raw := MakeRatingMatrix([]float64{
+		4,
+		2,
+		0}, 3, 1)
+	pred, ok := weightedMean(raw, 2, 0, []Neighbor{{0, 0.5}, {1, -0.5}}, Options{}, ratingStats{})
+	Assert(t, ok && pred == 1, pred)
+}
+
+func TestPredict(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		2, 3, 4, 1, 5,
+		3, 0, 3, 3, 0,
+		4, 4, 1, 2, 3,
+		2, 4, 0, 3, 4,
+		3, 1, 3, 0, 4}, 5, 5)
+	opts := Options{K: 2}
+	// same estimate as the recommendations
+	prods, scores, err := GetRecommendationsWithOptions(prefs, 1, nil, opts)
+	Assert(t, err == nil)
+	for idx, prod := range prods {
+		item, _ := strconv.Atoi(prod)
+		pred, err := Predict(prefs, 1, item, opts)
+		Assert(t, err == nil)
+		Assert(t, math.Abs(pred.Rating-scores[idx]) < 1e-9, pred.Rating, scores[idx])
+		Assert(t, pred.Neighbors == 2)
+		Assert(t, pred.Confidence > 0 && pred.Confidence < 1)
+	}
+	// fewer neighbors, less confidence
+	few, _ := Predict(prefs, 1, 4, Options{K: 1})
+	all, _ := Predict(prefs, 1, 4, Options{})
+	Assert(t, few.Neighbors == 1 && all.Neighbors == 4)
+	Assert(t, few.Confidence < all.Confidence)
+	// NaN is missing, like 0
+	missing := prefs.Copy()
+	missing.Set(3, 2, math.NaN())
+	nan_pred, err := Predict(missing, 1, 4, Options{Normalization: ZScore})
+	zero_pred, _ := Predict(prefs, 1, 4, Options{Normalization: ZScore})
+	Assert(t, err == nil && nan_pred == zero_pred, nan_pred, zero_pred)
 
+	_, err = Predict(prefs, 1, 5, opts)
+	Assert(t, err != nil)
+	// nobody rated the product
+	prefs.Set(0, 4, 0)
+	prefs.Set(2, 4, 0)
+	prefs.Set(3, 4, 0)
+	prefs.Set(4, 4, 0)
+	_, err = Predict(prefs, 1, 4, opts)
+	Assert(t, err != nil)
 }
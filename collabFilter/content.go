@@ -0,0 +1,147 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// How the words of a product's text are weighted.
+type TermWeighting int
+
+const (
+	// term count * log((1 + n) / (1 + document count)) + 1
+	TFIDF TermWeighting = iota
+	// Okapi BM25, with k1 = 1.2 and b = 0.75: term counts saturate, and long texts are penalized
+	BM25
+)
+
+// lower-cased words of a text
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Content-based recommendations: products are described by the words of their text (title, description,
+// tags...), and a user's profile is the sum of the vectors of the products they liked. Products are ranked by
+// the CosineSim of their vector with the profile, so new products without any ratings can be recommended.
+type ContentBased struct {
+	// word -> index in the vectors
+	Vocabulary map[string]int
+	// the weighted words of each product (products x words)
+	Vectors [][]float64
+
+	prefs *DenseMatrix
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing), the text of each product (e.g. title, description
+// and tags joined with spaces; repeat a field to give it more weight) and the term weighting.
+// Error if there isn't a text for every product.
+func NewContentBased(prefs *DenseMatrix, texts []string, weighting TermWeighting) (*ContentBased, error) {
+	if len(texts) != prefs.Cols() {
+		return nil, errors.New("need a text for every product")
+	}
+	model := &ContentBased{Vocabulary: make(map[string]int), Vectors: make([][]float64, len(texts)), prefs: replaceNA(prefs)}
+	counts := make([]map[int]float64, len(texts))
+	lengths := make([]float64, len(texts))
+	doc_counts := make([]float64, 0)
+	avg_length := float64(0)
+	for item, text := range texts {
+		counts[item] = make(map[int]float64)
+		for _, word := range tokenize(text) {
+			idx, ok := model.Vocabulary[word]
+			if !ok {
+				idx = len(model.Vocabulary)
+				model.Vocabulary[word] = idx
+				doc_counts = append(doc_counts, 0)
+			}
+			if counts[item][idx] == 0 {
+				doc_counts[idx]++
+			}
+			counts[item][idx]++
+			lengths[item]++
+		}
+		avg_length += lengths[item] / float64(len(texts))
+	}
+	n := float64(len(texts))
+	const k1, b = 1.2, 0.75
+	for item := range texts {
+		model.Vectors[item] = make([]float64, len(model.Vocabulary))
+		for idx, tf := range counts[item] {
+			switch weighting {
+			case BM25:
+				idf := math.Log(1 + (n-doc_counts[idx]+0.5)/(doc_counts[idx]+0.5))
+				model.Vectors[item][idx] = idf * tf * (k1 + 1) / (tf + k1*(1-b+b*lengths[item]/avg_length))
+			default:
+				model.Vectors[item][idx] = tf * (math.Log((1+n)/(1+doc_counts[idx])) + 1)
+			}
+		}
+	}
+	return model, nil
+}
+
+// Returns the profile of a user: the sum of the vectors of the products they liked, i.e. rated at least
+// as high as their mean rating, weighted by the rating. nil if they haven't liked anything.
+func (m *ContentBased) Profile(user int) []float64 {
+	ratings := m.prefs.RowCopy(user)
+	user_mean := mean(rated(ratings))
+	var profile []float64
+	for item, rating := range ratings {
+		if rating == 0 || rating < user_mean {
+			continue
+		}
+		if profile == nil {
+			profile = make([]float64, len(m.Vocabulary))
+		}
+		for idx, val := range m.Vectors[item] {
+			profile[idx] += rating * val
+		}
+	}
+	return profile
+}
+
+func (m *ContentBased) score(profile []float64, item int) float64 {
+	sim := CosineSim(profile, m.Vectors[item])
+	if math.IsNaN(sim) {
+		// a product without any words
+		return 0
+	}
+	return sim
+}
+
+// Returns the cosine similarity between a product and the profile of a user. Error if out of range, or if
+// the user hasn't liked any product.
+func (m *ContentBased) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	profile := m.Profile(user)
+	if profile == nil {
+		return 0, errors.New("user hasn't liked any product")
+	}
+	return m.score(profile, item), nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, rated or not by anybody
+// else. Returns products and similarities with the user's profile in descending order.
+func (m *ContentBased) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	profile := m.Profile(user)
+	if profile == nil {
+		return nil, nil, errors.New("user hasn't liked any product")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			scores[i] = m.score(profile, i)
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
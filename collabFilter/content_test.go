@@ -0,0 +1,73 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func testTexts() []string {
+	return []string{
+		"Spiderman: superhero action in New York",
+		"Big Momma's House: comedy",
+		"Vanilla Sky: sci-fi thriller",
+		"Pacific Rim: giant robots, action, sci-fi",
+		"The Amazing Spiderman: superhero action"}
+}
+
+func TestTokenize(t *testing.T) {
+	words := tokenize("Big Momma's House: sci-fi!")
+	Assert(t, len(words) == 6 && words[0] == "big" && words[2] == "s" && words[5] == "fi", words)
+}
+
+func TestContentBased(t *testing.T) {
+	// user 0 liked Spiderman, and nobody rated The Amazing Spiderman yet
+	prefs := MakeRatingMatrix([]float64{
+		5, 1, 0, 0, 0,
+		0, 4, 4, 0, 0}, 2, 5)
+	for _, weighting := range []TermWeighting{TFIDF, BM25} {
+		model, err := NewContentBased(prefs, testTexts(), weighting)
+		Assert(t, err == nil)
+		Assert(t, len(model.Vectors[0]) == len(model.Vocabulary))
+		prods, scores, err := model.GetRecommendations(0, testTexts())
+		Assert(t, err == nil && len(prods) == 3, prods)
+		Assert(t, prods[0] == testTexts()[4] && scores[0] > scores[1], prods, scores)
+		// sci-fi for user 1
+		prods, _, _ = model.GetRecommendations(1, nil)
+		Assert(t, prods[0] == "3", prods)
+
+		pred, err := model.Predict(0, 4)
+		Assert(t, err == nil && pred == scores[0])
+	}
+	// only the liked products make up the profile
+	model, _ := NewContentBased(prefs, testTexts(), TFIDF)
+	Assert(t, model.Profile(0)[model.Vocabulary["comedy"]] == 0)
+	Assert(t, model.Profile(0)[model.Vocabulary["superhero"]] > 0)
+
+	_, err := NewContentBased(prefs, testTexts()[:2], TFIDF)
+	Assert(t, err != nil)
+	_, err = model.Predict(0, 5)
+	Assert(t, err != nil)
+	assertOwnCopy(t, func(prefs *DenseMatrix) Recommender {
+		model, _ := NewContentBased(prefs, testTexts(), TFIDF)
+		return model
+	})
+}
+
+func TestBlend(t *testing.T) {
+	prefs := testPrefs()
+	content, _ := NewContentBased(prefs, testTexts(), TFIDF)
+	cf := NewItemBased(prefs, 2, CosineSim)
+	blend, err := NewBlend(prefs, []Recommender{cf, content}, []float64{0.25, 0.75})
+	Assert(t, err == nil)
+	a, _ := cf.Predict(1, 1)
+	b, _ := content.Predict(1, 1)
+	pred, err := blend.Predict(1, 1)
+	Assert(t, err == nil && math.Abs(pred-(0.25*a+0.75*b)) < 1e-9, pred)
+	prods, _, err := blend.GetRecommendations(1, nil)
+	Assert(t, err == nil && len(prods) == 2, prods)
+
+	_, err = NewBlend(prefs, []Recommender{cf}, nil)
+	Assert(t, err != nil)
+}
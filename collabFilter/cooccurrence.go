@@ -0,0 +1,155 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+)
+
+// How co-occurrence counts are turned into the association of two products a and b, where n_ab is the
+// number of transactions with both, n_a and n_b those with each, and n the number of transactions.
+type CooccurrenceMeasure int
+
+const (
+	// n_ab
+	CooccurrenceCount CooccurrenceMeasure = iota
+	// n_ab * n / (n_a * n_b): how much more often than by chance a and b are bought together
+	CooccurrenceLift
+	// n_ab / sqrt(n_a * n_b)
+	CooccurrenceCosine
+	// n_ab / (n_a + n_b - n_ab)
+	CooccurrenceJaccard
+	// Dunning's log-likelihood ratio (G^2) of the 2x2 contingency table of a and b, see
+	// "Accurate Methods for the Statistics of Surprise and Coincidence" (1993). Doesn't overrate
+	// pairs of rare products like lift does.
+	CooccurrenceLLR
+)
+
+// "People who bought X also bought Y" recommendations from transactions (order baskets, sessions...)
+// rather than a user/product matrix. Two products are related if they appear in the same transactions.
+type Cooccurrence struct {
+	Measure CooccurrenceMeasure
+	// number of transactions, and number of transactions containing each product
+	Transactions int
+	ItemCounts   []int
+
+	// number of transactions containing both products, for the pairs that appear together
+	pairs []map[int]int
+}
+
+// Params: the transactions, each a list of product indices (repeated products count once), and the measure.
+// Error if a product index is negative.
+func NewCooccurrence(transactions [][]int, measure CooccurrenceMeasure) (*Cooccurrence, error) {
+	n_items := 0
+	for _, transaction := range transactions {
+		for _, item := range transaction {
+			if item < 0 {
+				return nil, errors.New("product indices can't be negative")
+			}
+			if item+1 > n_items {
+				n_items = item + 1
+			}
+		}
+	}
+	model := &Cooccurrence{Measure: measure, Transactions: len(transactions), ItemCounts: make([]int, n_items), pairs: make([]map[int]int, n_items)}
+	for i := range model.pairs {
+		model.pairs[i] = make(map[int]int)
+	}
+	for _, transaction := range transactions {
+		items := make(map[int]bool, len(transaction))
+		for _, item := range transaction {
+			items[item] = true
+		}
+		for a := range items {
+			model.ItemCounts[a]++
+			for b := range items {
+				if a != b {
+					model.pairs[a][b]++
+				}
+			}
+		}
+	}
+	return model, nil
+}
+
+// x * ln(x), 0 for x = 0
+func xLogX(x float64) float64 {
+	if x == 0 {
+		return 0
+	}
+	return x * math.Log(x)
+}
+
+// unnormalized Shannon entropy of counts
+func entropy(counts ...float64) float64 {
+	sum, sum_xlogx := float64(0), float64(0)
+	for _, x := range counts {
+		sum += x
+		sum_xlogx += xLogX(x)
+	}
+	return xLogX(sum) - sum_xlogx
+}
+
+// Dunning's log-likelihood ratio of a 2x2 contingency table: k11 transactions with both products, k12 with
+// only the first, k21 with only the second, k22 with neither.
+func logLikelihoodRatio(k11, k12, k21, k22 float64) float64 {
+	row_entropy := entropy(k11+k12, k21+k22)
+	col_entropy := entropy(k11+k21, k12+k22)
+	matrix_entropy := entropy(k11, k12, k21, k22)
+	if row_entropy+col_entropy < matrix_entropy {
+		// round off error
+		return 0
+	}
+	return 2 * (row_entropy + col_entropy - matrix_entropy)
+}
+
+// Returns the association of products a and b under the model's measure. 0 if they never appear together.
+func (m *Cooccurrence) Score(a, b int) float64 {
+	if a < 0 || b < 0 || a >= len(m.pairs) || b >= len(m.pairs) || a == b {
+		return 0
+	}
+	n_ab := float64(m.pairs[a][b])
+	if n_ab == 0 {
+		return 0
+	}
+	n_a, n_b, n := float64(m.ItemCounts[a]), float64(m.ItemCounts[b]), float64(m.Transactions)
+	switch m.Measure {
+	case CooccurrenceLift:
+		return n_ab * n / (n_a * n_b)
+	case CooccurrenceCosine:
+		return n_ab / math.Sqrt(n_a*n_b)
+	case CooccurrenceJaccard:
+		return n_ab / (n_a + n_b - n_ab)
+	case CooccurrenceLLR:
+		return logLikelihoodRatio(n_ab, n_a-n_ab, n_b-n_ab, n-n_a-n_b+n_ab)
+	}
+	return n_ab
+}
+
+// Returns the products bought together with item, and their scores, in descending order.
+// Error if out of range.
+func (m *Cooccurrence) Related(item int, products []string) ([]string, []float64, error) {
+	return m.RelatedToBasket([]int{item}, products)
+}
+
+// Returns the products bought together with the products of a basket, scored by the sum of their
+// associations with each product of the basket, in descending order. Products already in the basket
+// are left out. Error if out of range.
+func (m *Cooccurrence) RelatedToBasket(basket []int, products []string) ([]string, []float64, error) {
+	in_basket := make(map[int]bool, len(basket))
+	for _, item := range basket {
+		if item < 0 || item >= len(m.pairs) {
+			return nil, nil, errors.New("product index out of range")
+		}
+		in_basket[item] = true
+	}
+	scores := make(map[int]float64)
+	for a := range in_basket {
+		for b := range m.pairs[a] {
+			if !in_basket[b] {
+				scores[b] += m.Score(a, b)
+			}
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
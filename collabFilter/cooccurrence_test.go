@@ -0,0 +1,59 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+)
+
+func testTransactions() [][]int {
+	return [][]int{
+		{0, 1, 2},
+		{0, 1},
+		{0, 2},
+		{1, 3},
+		{3},
+		{0, 1, 1}}
+}
+
+func TestCooccurrence(t *testing.T) {
+	model, err := NewCooccurrence(testTransactions(), CooccurrenceCount)
+	Assert(t, err == nil)
+	Assert(t, model.Transactions == 6 && model.ItemCounts[0] == 4 && model.ItemCounts[1] == 4, model.ItemCounts)
+	Assert(t, model.Score(0, 1) == 3 && model.Score(1, 0) == 3 && model.Score(2, 3) == 0)
+
+	model.Measure = CooccurrenceLift
+	Assert(t, model.Score(0, 1) == 1.125 && model.Score(0, 2) == 1.5)
+	model.Measure = CooccurrenceCosine
+	Assert(t, math.Abs(model.Score(0, 2)-1/math.Sqrt(2)) < 1e-9)
+	model.Measure = CooccurrenceJaccard
+	Assert(t, model.Score(0, 1) == 0.6)
+	model.Measure = CooccurrenceLLR
+	Assert(t, model.Score(0, 1) > 0)
+
+	_, err = NewCooccurrence([][]int{{0, -1}}, CooccurrenceCount)
+	Assert(t, err != nil)
+}
+
+func TestLogLikelihoodRatio(t *testing.T) {
+	Assert(t, math.Abs(logLikelihoodRatio(1, 0, 0, 1)-2.772588722239781) < 1e-9)
+	Assert(t, math.Abs(logLikelihoodRatio(10, 0, 0, 10)-27.725887222397812) < 1e-9)
+	// independent
+	Assert(t, logLikelihoodRatio(1, 1, 1, 1) == 0)
+}
+
+func TestRelated(t *testing.T) {
+	model, _ := NewCooccurrence(testTransactions(), CooccurrenceCount)
+	products := []string{"tent", "stove", "lantern", "map"}
+	prods, scores, err := model.Related(0, products)
+	Assert(t, err == nil)
+	Assert(t, len(prods) == 2 && prods[0] == "stove" && prods[1] == "lantern", prods)
+	Assert(t, scores[0] == 3 && scores[1] == 2, scores)
+
+	// a stove goes with the tent and with the map
+	prods, scores, err = model.RelatedToBasket([]int{0, 3}, products)
+	Assert(t, err == nil)
+	Assert(t, len(prods) == 2 && prods[0] == "stove" && scores[0] == 4, prods, scores)
+
+	_, _, err = model.Related(4, products)
+	Assert(t, err != nil)
+}
@@ -0,0 +1,157 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// similarity between two products (indices). Error if a product isn't known to it.
+type ItemSimilarity func(i, j int) (float64, error)
+
+func productsOutOfRange(i, j, n int) bool {
+	return i < 0 || j < 0 || i >= n || j >= n
+}
+
+// Cosine similarity between the factors of two products, from a factors x products matrix such as the Y
+// of ALS.TrainEALS (use Transpose for products x factors matrices like ALS.TensorModel's B).
+func FactorSimilarity(Y *DenseMatrix) ItemSimilarity {
+	cols := make([][]float64, Y.Cols())
+	for i := range cols {
+		cols[i] = Y.ColCopy(i)
+	}
+	return func(i, j int) (float64, error) {
+		if productsOutOfRange(i, j, len(cols)) {
+			return 0, errors.New("product index out of range")
+		}
+		sim := CosineSim(cols[i], cols[j])
+		if math.IsNaN(sim) {
+			return 0, nil
+		}
+		return sim, nil
+	}
+}
+
+// Similarity between two products from a products x products matrix, such as ItemBased's Similarities.
+func MatrixSimilarity(S *DenseMatrix) ItemSimilarity {
+	return func(i, j int) (float64, error) {
+		if productsOutOfRange(i, j, S.Rows()) || j >= S.Cols() {
+			return 0, errors.New("product index out of range")
+		}
+		return S.Get(i, j), nil
+	}
+}
+
+// Maximal Marginal Relevance re-ranking, from Carbonell and Goldstein "The Use of MMR, Diversity-Based
+// Reranking for Reordering Documents and Producing Summaries" (SIGIR 1998). Products are picked one at a time,
+// each maximizing Lambda * relevance - (1 - Lambda) * (largest similarity with the products already picked),
+// so that the list isn't made of near-identical products. Lambda = 1 keeps the original order.
+type MMR struct {
+	Lambda     float64
+	Similarity ItemSimilarity
+	// if set, the category of each product, and at most MaxPerCategory products of a category are picked.
+	Categories     []int
+	MaxPerCategory int
+}
+
+// Re-ranks recommendations, e.g. from GetRecommendations or ALS.GetTopNRecommendations, and returns the first
+// n (0 returns all of them). products is the list the recommendations were named with (nil for indices).
+// The scores are scaled to [0, 1] to be comparable with the similarities; if nil, the relevance decreases
+// with the position instead. Returns the products and their original scores (or relevances).
+// Error if a recommendation isn't one of the products, or has no category or similarity.
+func (m MMR) Rerank(recommendations []string, scores []float64, products []string, n int) ([]string, []float64, error) {
+	if scores != nil && len(scores) != len(recommendations) {
+		return nil, nil, errors.New("need a score for every recommendation")
+	}
+	names := make(map[string]int, len(products))
+	for idx, name := range products {
+		names[name] = idx
+	}
+	items := make([]int, len(recommendations))
+	relevance := make([]float64, len(recommendations))
+	for pos, name := range recommendations {
+		item, ok := names[name]
+		if products == nil {
+			var err error
+			item, err = strconv.Atoi(name)
+			ok = err == nil
+		}
+		if !ok {
+			return nil, nil, errors.New("unknown product " + name)
+		}
+		if m.Categories != nil && (item < 0 || item >= len(m.Categories)) {
+			return nil, nil, errors.New("no category for product " + name)
+		}
+		items[pos] = item
+		if scores != nil {
+			relevance[pos] = scores[pos]
+		} else {
+			relevance[pos] = 1 - float64(pos)/float64(len(recommendations))
+		}
+	}
+	// scale to [0, 1]
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, rel := range relevance {
+		low, high = math.Min(low, rel), math.Max(high, rel)
+	}
+	scaled := make([]float64, len(relevance))
+	for pos, rel := range relevance {
+		if high > low {
+			scaled[pos] = (rel - low) / (high - low)
+		} else {
+			scaled[pos] = 1
+		}
+	}
+
+	if n <= 0 || n > len(recommendations) {
+		n = len(recommendations)
+	}
+	picked := make([]bool, len(recommendations))
+	// largest similarity of each candidate with the picked products
+	max_sims := make([]float64, len(recommendations))
+	per_category := make(map[int]int)
+	prods := make([]string, 0, n)
+	vals := make([]float64, 0, n)
+	for len(prods) < n {
+		best, best_score := -1, math.Inf(-1)
+		for pos := range recommendations {
+			if picked[pos] {
+				continue
+			}
+			if m.Categories != nil && m.MaxPerCategory > 0 && per_category[m.Categories[items[pos]]] >= m.MaxPerCategory {
+				continue
+			}
+			score := m.Lambda * scaled[pos]
+			if len(prods) > 0 {
+				score -= (1 - m.Lambda) * max_sims[pos]
+			}
+			if score > best_score {
+				best, best_score = pos, score
+			}
+		}
+		if best < 0 {
+			// the rest are in categories that are full
+			break
+		}
+		picked[best] = true
+		if m.Categories != nil {
+			per_category[m.Categories[items[best]]]++
+		}
+		prods = append(prods, recommendations[best])
+		vals = append(vals, relevance[best])
+		for pos := range recommendations {
+			if !picked[pos] && m.Similarity != nil {
+				sim, err := m.Similarity(items[pos], items[best])
+				if err != nil {
+					return nil, nil, err
+				}
+				if len(prods) == 1 || sim > max_sims[pos] {
+					max_sims[pos] = sim
+				}
+			}
+		}
+	}
+	return prods, vals, nil
+}
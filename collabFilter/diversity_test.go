@@ -0,0 +1,65 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func TestFactorSimilarity(t *testing.T) {
+	// factors x products: products 0 and 1 point the same way
+	Y := MakeRatingMatrix([]float64{
+		1, 2, 0,
+		1, 2, 1}, 2, 3)
+	sim := FactorSimilarity(Y)
+	same, _ := sim(0, 1)
+	other, err := sim(0, 2)
+	Assert(t, err == nil && math.Abs(same-1) < 1e-9 && other < 1, same, other)
+	_, err = sim(0, 3)
+	Assert(t, err != nil)
+	S := Eye(3)
+	S.Set(1, 2, 0.5)
+	same, err = MatrixSimilarity(S)(1, 2)
+	Assert(t, err == nil && same == 0.5, same)
+	_, err = MatrixSimilarity(S)(-1, 2)
+	Assert(t, err != nil)
+}
+
+func TestMMR(t *testing.T) {
+	// Spiderman and The Amazing Spiderman are near-identical
+	products := []string{"Spiderman", "The Amazing Spiderman", "Vanilla Sky", "Big Momma's House"}
+	S := Eye(4)
+	S.Set(0, 1, 0.9)
+	S.Set(1, 0, 0.9)
+	recs := []string{"Spiderman", "The Amazing Spiderman", "Vanilla Sky", "Big Momma's House"}
+	scores := []float64{5, 4.9, 4, 3}
+
+	prods, vals, err := MMR{Lambda: 1, Similarity: MatrixSimilarity(S)}.Rerank(recs, scores, products, 0)
+	Assert(t, err == nil && prods[1] == "The Amazing Spiderman" && vals[1] == 4.9, prods, vals)
+
+	prods, vals, err = MMR{Lambda: 0.5, Similarity: MatrixSimilarity(S)}.Rerank(recs, scores, products, 3)
+	Assert(t, err == nil && len(prods) == 3, prods)
+	Assert(t, prods[0] == "Spiderman" && prods[1] == "Vanilla Sky" && vals[1] == 4, prods, vals)
+
+	// without scores, by position
+	prods, _, _ = MMR{Lambda: 0.5, Similarity: MatrixSimilarity(S)}.Rerank(recs, nil, products, 2)
+	Assert(t, prods[1] == "Vanilla Sky", prods)
+
+	// at most one superhero movie
+	mmr := MMR{Lambda: 1, Categories: []int{0, 0, 1, 2}, MaxPerCategory: 1}
+	prods, _, err = mmr.Rerank(recs, scores, products, 0)
+	Assert(t, err == nil && len(prods) == 3 && prods[1] == "Vanilla Sky", prods)
+
+	// indices when there are no product names
+	prods, _, err = MMR{Lambda: 0.5, Similarity: MatrixSimilarity(S)}.Rerank([]string{"0", "1", "2"}, nil, nil, 0)
+	Assert(t, err == nil && prods[1] == "2", prods)
+
+	_, _, err = mmr.Rerank([]string{"Pacific Rim"}, nil, products, 0)
+	Assert(t, err != nil)
+	_, _, err = mmr.Rerank(recs, scores[:2], products, 0)
+	Assert(t, err != nil)
+	// a similarity matrix that doesn't know every product
+	_, _, err = MMR{Lambda: 0.5, Similarity: MatrixSimilarity(Eye(2))}.Rerank(recs, scores, products, 0)
+	Assert(t, err != nil)
+}
@@ -0,0 +1,180 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// How much one neighbor contributed to a recommendation: a similar user and their rating of the product
+// (user-based), or one of the user's own products and their rating of it (item-based).
+type Contribution struct {
+	// the neighbor user, or the user's product (index), and the product's name if products are given
+	Index   int
+	Product string
+	// the similarity with the user (user-based) or with the recommended product (item-based), and the rating
+	Similarity float64
+	Rating     float64
+	// s * r / sum(|s|): the share of the score due to this neighbor. The contributions add up to the score,
+	// or to its deviation from the user's mean with MeanCentering and ZScore (where the neighbors' z-scores
+	// are scaled back by the user's standard deviation).
+	Contribution float64
+}
+
+// Why a product was recommended, e.g. for a "Because you liked..." list.
+type Explanation struct {
+	Item    int
+	Product string
+	Score   float64
+	// the neighbors that contributed the most, largest contribution first
+	Because []Contribution
+}
+
+// the name of product idx, or its index if there are no names
+func productName(products []string, idx int) string {
+	if products != nil {
+		return products[idx]
+	}
+	return strconv.Itoa(idx)
+}
+
+// the contributions s * x / sum(|s|) of the neighbors, given their (normalized) ratings x, keeping the n
+// largest (0 keeps all of them).
+func contributions(neighbors []Neighbor, ratings, normalized []float64, n int) []Contribution {
+	sims := float64(0)
+	for _, neighbor := range neighbors {
+		sims += math.Abs(neighbor.Similarity)
+	}
+	because := make([]Contribution, len(neighbors))
+	for idx, neighbor := range neighbors {
+		because[idx] = Contribution{Index: neighbor.Index, Similarity: neighbor.Similarity, Rating: ratings[idx],
+			Contribution: neighbor.Similarity * normalized[idx] / sims}
+	}
+	sort.SliceStable(because, func(a, b int) bool { return because[a].Contribution > because[b].Contribution })
+	if n > 0 && n < len(because) {
+		because = because[:n]
+	}
+	return because
+}
+
+// sorts explanations by descending score, like rank
+func sortExplanations(explanations []Explanation) {
+	sort.Slice(explanations, func(a, b int) bool {
+		if explanations[a].Score == explanations[b].Score {
+			return explanations[a].Item < explanations[b].Item
+		}
+		return explanations[a].Score > explanations[b].Score
+	})
+}
+
+func explainUserBased(prefs *DenseMatrix, user, item int, sims []Neighbor, stats ratingStats, products []string, opts Options, n int) (Explanation, bool) {
+	neighbors := itemNeighbors(prefs, item, sims, opts)
+	score, ok := weightedMean(prefs, user, item, neighbors, opts, stats)
+	if !ok {
+		return Explanation{}, false
+	}
+	ratings := make([]float64, len(neighbors))
+	normalized := make([]float64, len(neighbors))
+	for idx, neighbor := range neighbors {
+		ratings[idx] = ratingOf(prefs, neighbor.Index, item)
+		normalized[idx] = normalizedRating(prefs, neighbor.Index, item, opts, stats)
+		if opts.Normalization == ZScore {
+			// as in weightedMean
+			normalized[idx] *= stats.stds[user]
+		}
+	}
+	// neighbors are users, so they don't have product names
+	return Explanation{item, productName(products, item), score, contributions(neighbors, ratings, normalized, n)}, true
+}
+
+// Explains the prediction of a user's (row index) rating of a product, as made by GetRecommendationsWithOptions
+// (or GetRecommendations, with Options{}): returns the n neighbors who contributed the most (0 returns all of
+// them), with their ratings of the product. Error if out of range, or if no neighbor rated the product.
+func Explain(prefs *DenseMatrix, user, item int, products []string, opts Options, n int) (Explanation, error) {
+	if user >= prefs.Rows() || item >= prefs.Cols() || user < 0 || item < 0 {
+		return Explanation{}, errors.New("user/product index out of range")
+	}
+	sims := userSimilarities(prefs, user, opts)
+	explanation, ok := explainUserBased(prefs, user, item, sims, neighborhoodStats(prefs, user, sims, opts), products, opts, n)
+	if !ok {
+		return Explanation{}, errors.New("no neighbors to predict from")
+	}
+	return explanation, nil
+}
+
+// Same recommendations as GetRecommendationsWithOptions, in descending order, each explained by its n most
+// contributing neighbors (0 returns all of them).
+func ExplainRecommendations(prefs *DenseMatrix, user int, products []string, opts Options, n int) ([]Explanation, error) {
+	if user >= prefs.Rows() || user < 0 {
+		return nil, errors.New("user index out of range")
+	}
+	sims := userSimilarities(prefs, user, opts)
+	stats := neighborhoodStats(prefs, user, sims, opts)
+	user_ratings := userRatings(prefs, user)
+	explanations := make([]Explanation, 0)
+	for item := 0; item < prefs.Cols(); item++ {
+		if user_ratings[item] == 0 {
+			if explanation, ok := explainUserBased(prefs, user, item, sims, stats, products, opts, n); ok {
+				explanations = append(explanations, explanation)
+			}
+		}
+	}
+	sortExplanations(explanations)
+	return explanations, nil
+}
+
+func (m *ItemBased) explain(user, item int, products []string, n int) (Explanation, bool) {
+	score, ok := m.predict(user, item)
+	if !ok {
+		return Explanation{}, false
+	}
+	// the user's own products that were used, as in itemNeighborsMean
+	neighbors := make([]Neighbor, 0)
+	ratings := make([]float64, 0)
+	for _, neighbor := range m.Neighbors[item] {
+		if rating := m.prefs.Get(user, neighbor.Index); rating != 0 && neighbor.Similarity != 0 {
+			neighbors = append(neighbors, neighbor)
+			ratings = append(ratings, rating)
+		}
+	}
+	because := contributions(neighbors, ratings, ratings, n)
+	for idx := range because {
+		because[idx].Product = productName(products, because[idx].Index)
+	}
+	return Explanation{item, productName(products, item), score, because}, true
+}
+
+// Explains the prediction of a user's rating of a product: returns the n products of the user that
+// contributed the most (0 returns all of them), with the user's ratings of them.
+// Error if out of range, or if the user hasn't rated any neighbor of the product.
+func (m *ItemBased) Explain(user, item int, products []string, n int) (Explanation, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return Explanation{}, errors.New("user/product index out of range")
+	}
+	explanation, ok := m.explain(user, item, products, n)
+	if !ok {
+		return Explanation{}, errors.New("user has not rated any neighbor of the product")
+	}
+	return explanation, nil
+}
+
+// Same recommendations as GetRecommendations, in descending order, each explained by the n products of the
+// user that contributed the most (0 returns all of them).
+func (m *ItemBased) ExplainRecommendations(user int, products []string, n int) ([]Explanation, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, errors.New("user index out of range")
+	}
+	explanations := make([]Explanation, 0)
+	for item := 0; item < m.prefs.Cols(); item++ {
+		if m.prefs.Get(user, item) == 0 {
+			if explanation, ok := m.explain(user, item, products, n); ok {
+				explanations = append(explanations, explanation)
+			}
+		}
+	}
+	sortExplanations(explanations)
+	return explanations, nil
+}
@@ -0,0 +1,60 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	prefs := testPrefs()
+	products := []string{"Spiderman", "Big Momma's House", "Vanilla Sky", "Pacific Rim", "The Mask"}
+	for _, opts := range []Options{{}, {K: 2}, {K: 3, Normalization: MeanCentering}, {Normalization: ZScore}} {
+		prods, scores, _ := GetRecommendationsWithOptions(prefs, 1, products, opts)
+		explanations, err := ExplainRecommendations(prefs, 1, products, opts, 0)
+		Assert(t, err == nil && len(explanations) == len(prods))
+		for idx, explanation := range explanations {
+			Assert(t, explanation.Product == prods[idx] && math.Abs(explanation.Score-scores[idx]) < 1e-9)
+			total := float64(0)
+			for pos, c := range explanation.Because {
+				Assert(t, c.Rating == prefs.Get(c.Index, explanation.Item) && c.Index != 1)
+				if pos > 0 {
+					Assert(t, c.Contribution <= explanation.Because[pos-1].Contribution)
+				}
+				total += c.Contribution
+			}
+			if opts.Normalization == MeanCentering || opts.Normalization == ZScore {
+				total += UserMeans(prefs)[1]
+			}
+			Assert(t, math.Abs(total-explanation.Score) < 1e-9, total, explanation)
+		}
+	}
+	explanation, err := Explain(prefs, 1, 4, products, Options{}, 2)
+	Assert(t, err == nil && explanation.Product == "The Mask" && len(explanation.Because) == 2)
+	_, err = Explain(prefs, 1, 5, products, Options{}, 2)
+	Assert(t, err != nil)
+}
+
+func TestItemBasedExplain(t *testing.T) {
+	prefs := testPrefs()
+	products := []string{"Spiderman", "Big Momma's House", "Vanilla Sky", "Pacific Rim", "The Mask"}
+	model := NewItemBased(prefs, 3, CosineSim)
+	prods, scores, _ := model.GetRecommendations(1, products)
+	explanations, err := model.ExplainRecommendations(1, products, 1)
+	Assert(t, err == nil && len(explanations) == len(prods))
+	for idx, explanation := range explanations {
+		Assert(t, explanation.Product == prods[idx] && explanation.Score == scores[idx])
+		// because you liked one of your own products
+		Assert(t, len(explanation.Because) == 1)
+		c := explanation.Because[0]
+		Assert(t, c.Product == products[c.Index] && c.Rating == prefs.Get(1, c.Index), c)
+	}
+	explanation, err := model.Explain(1, 4, nil, 0)
+	Assert(t, err == nil && explanation.Product == "4")
+	total := float64(0)
+	for _, c := range explanation.Because {
+		total += c.Contribution
+	}
+	Assert(t, math.Abs(total-explanation.Score) < 1e-9)
+	_, err = model.Explain(5, 0, nil, 0)
+	Assert(t, err != nil)
+}
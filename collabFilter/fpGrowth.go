@@ -0,0 +1,255 @@
+package collabFilter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// a set of products (sorted indices) and the number of transactions containing all of them
+type Itemset struct {
+	Items []int
+	Count int
+	// fraction of the transactions containing the itemset
+	Support float64
+}
+
+// An association rule Antecedent -> Consequent, e.g. {tent, stove} -> lantern.
+type Rule struct {
+	Antecedent []int
+	Consequent int
+	// fraction of the transactions with the antecedent and the consequent
+	Support float64
+	// fraction of the transactions with the antecedent that also have the consequent
+	Confidence float64
+	// confidence over the support of the consequent: how much more likely the consequent is given the antecedent
+	Lift float64
+}
+
+// Formats the rule with product names, e.g. "{tent, stove} -> lantern".
+func (r Rule) Format(products []string) string {
+	name := func(item int) string {
+		if products != nil {
+			return products[item]
+		}
+		return fmt.Sprint(item)
+	}
+	names := make([]string, len(r.Antecedent))
+	for idx, item := range r.Antecedent {
+		names[idx] = name(item)
+	}
+	return "{" + strings.Join(names, ", ") + "} -> " + name(r.Consequent)
+}
+
+// a node of an FP-tree
+type fpNode struct {
+	item, count int
+	parent      *fpNode
+	children    map[int]*fpNode
+}
+
+// FP-tree: the transactions, restricted to their frequent products in descending order of frequency, stored
+// as a prefix tree, with the nodes of each product and the frequent products' counts.
+type fpTree struct {
+	root   *fpNode
+	nodes  map[int][]*fpNode
+	counts map[int]int
+}
+
+// builds an FP-tree from transactions (without repeated products) that occur the given number of times.
+func newFPTree(transactions [][]int, weights []int, min_count int) *fpTree {
+	tree := &fpTree{root: &fpNode{item: -1, children: make(map[int]*fpNode)}, nodes: make(map[int][]*fpNode), counts: make(map[int]int)}
+	for idx, transaction := range transactions {
+		for _, item := range transaction {
+			tree.counts[item] += weights[idx]
+		}
+	}
+	for item, count := range tree.counts {
+		if count < min_count {
+			delete(tree.counts, item)
+		}
+	}
+	for idx, transaction := range transactions {
+		items := make([]int, 0, len(transaction))
+		for _, item := range transaction {
+			if _, ok := tree.counts[item]; ok {
+				items = append(items, item)
+			}
+		}
+		sort.Slice(items, func(a, b int) bool {
+			if tree.counts[items[a]] == tree.counts[items[b]] {
+				return items[a] < items[b]
+			}
+			return tree.counts[items[a]] > tree.counts[items[b]]
+		})
+		node := tree.root
+		for _, item := range items {
+			child, ok := node.children[item]
+			if !ok {
+				child = &fpNode{item: item, parent: node, children: make(map[int]*fpNode)}
+				node.children[item] = child
+				tree.nodes[item] = append(tree.nodes[item], child)
+			}
+			child.count += weights[idx]
+			node = child
+		}
+	}
+	return tree
+}
+
+// appends the frequent itemsets of the tree, each extended with suffix, to itemsets.
+func (tree *fpTree) mine(suffix []int, min_count int, itemsets []Itemset) []Itemset {
+	items := make([]int, 0, len(tree.counts))
+	for item := range tree.counts {
+		items = append(items, item)
+	}
+	sort.Ints(items)
+	for _, item := range items {
+		itemset := append([]int{item}, suffix...)
+		sorted := append([]int(nil), itemset...)
+		sort.Ints(sorted)
+		itemsets = append(itemsets, Itemset{Items: sorted, Count: tree.counts[item]})
+		// conditional pattern base: the prefix paths of the item
+		paths := make([][]int, 0, len(tree.nodes[item]))
+		weights := make([]int, 0, len(tree.nodes[item]))
+		for _, node := range tree.nodes[item] {
+			path := make([]int, 0)
+			for parent := node.parent; parent != tree.root; parent = parent.parent {
+				path = append(path, parent.item)
+			}
+			if len(path) > 0 {
+				paths = append(paths, path)
+				weights = append(weights, node.count)
+			}
+		}
+		conditional := newFPTree(paths, weights, min_count)
+		if len(conditional.counts) > 0 {
+			itemsets = conditional.mine(itemset, min_count, itemsets)
+		}
+	}
+	return itemsets
+}
+
+// Finds the itemsets contained in at least a fraction minSupport of the transactions, with FP-Growth
+// (Han et al. "Mining Frequent Patterns without Candidate Generation", SIGMOD 2000).
+// Transactions are lists of product indices; repeated products count once. Error if a product index is
+// negative or minSupport isn't in (0, 1].
+func FrequentItemsets(transactions [][]int, minSupport float64) ([]Itemset, error) {
+	if minSupport <= 0 || minSupport > 1 {
+		return nil, errors.New("minimum support must be in (0, 1]")
+	}
+	unique := make([][]int, len(transactions))
+	weights := make([]int, len(transactions))
+	for idx, transaction := range transactions {
+		seen := make(map[int]bool, len(transaction))
+		for _, item := range transaction {
+			if item < 0 {
+				return nil, errors.New("product indices can't be negative")
+			}
+			if !seen[item] {
+				seen[item] = true
+				unique[idx] = append(unique[idx], item)
+			}
+		}
+		weights[idx] = 1
+	}
+	n := float64(len(transactions))
+	min_count := 1
+	for float64(min_count) < minSupport*n {
+		min_count++
+	}
+	itemsets := newFPTree(unique, weights, min_count).mine(nil, min_count, make([]Itemset, 0))
+	for idx := range itemsets {
+		itemsets[idx].Support = float64(itemsets[idx].Count) / n
+	}
+	return itemsets, nil
+}
+
+// Mines the rules with a single consequent whose itemset has a support of at least minSupport, and with
+// at least the given confidence and lift. Rules are sorted by descending confidence, then lift.
+func MineRules(transactions [][]int, minSupport, minConfidence, minLift float64) ([]Rule, error) {
+	itemsets, err := FrequentItemsets(transactions, minSupport)
+	if err != nil {
+		return nil, err
+	}
+	// every subset of a frequent itemset is frequent, so the counts of the antecedents are known
+	counts := make(map[string]int, len(itemsets))
+	for _, itemset := range itemsets {
+		counts[fmt.Sprint(itemset.Items)] = itemset.Count
+	}
+	n := float64(len(transactions))
+	rules := make([]Rule, 0)
+	for _, itemset := range itemsets {
+		if len(itemset.Items) < 2 {
+			continue
+		}
+		for idx, consequent := range itemset.Items {
+			antecedent := make([]int, 0, len(itemset.Items)-1)
+			antecedent = append(antecedent, itemset.Items[:idx]...)
+			antecedent = append(antecedent, itemset.Items[idx+1:]...)
+			confidence := float64(itemset.Count) / float64(counts[fmt.Sprint(antecedent)])
+			lift := confidence / (float64(counts[fmt.Sprint([]int{consequent})]) / n)
+			if confidence >= minConfidence && lift >= minLift {
+				rules = append(rules, Rule{antecedent, consequent, itemset.Support, confidence, lift})
+			}
+		}
+	}
+	sort.SliceStable(rules, func(a, b int) bool {
+		if rules[a].Confidence == rules[b].Confidence {
+			return rules[a].Lift > rules[b].Lift
+		}
+		return rules[a].Confidence > rules[b].Confidence
+	})
+	return rules, nil
+}
+
+// Recommends products by applying association rules to a basket.
+type AssociationRules struct {
+	Rules []Rule
+}
+
+// Params: the transactions, and the minimum support, confidence and lift of the rules. See MineRules.
+func NewAssociationRules(transactions [][]int, minSupport, minConfidence, minLift float64) (*AssociationRules, error) {
+	rules, err := MineRules(transactions, minSupport, minConfidence, minLift)
+	if err != nil {
+		return nil, err
+	}
+	return &AssociationRules{rules}, nil
+}
+
+// Applies the rules whose antecedent is in the basket. Returns the products they recommend that aren't in
+// the basket yet, in descending order of confidence, with the confidence and the rule that fired (the most
+// confident one, if several recommend a product).
+func (m *AssociationRules) Recommend(basket []int, products []string) ([]string, []float64, []Rule) {
+	in_basket := make(map[int]bool, len(basket))
+	for _, item := range basket {
+		in_basket[item] = true
+	}
+	fired := make(map[int]Rule)
+	for _, rule := range m.Rules {
+		if _, ok := fired[rule.Consequent]; ok || in_basket[rule.Consequent] {
+			continue
+		}
+		applies := true
+		for _, item := range rule.Antecedent {
+			applies = applies && in_basket[item]
+		}
+		if applies {
+			fired[rule.Consequent] = rule
+		}
+	}
+	ranked := make([]Neighbor, 0, len(fired))
+	for item, rule := range fired {
+		ranked = append(ranked, Neighbor{item, rule.Confidence})
+	}
+	sort.Sort(bySimilarity(ranked))
+	scores := make(map[int]float64, len(fired))
+	rules := make([]Rule, len(ranked))
+	for idx, n := range ranked {
+		scores[n.Index] = n.Similarity
+		rules[idx] = fired[n.Index]
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, rules
+}
@@ -0,0 +1,93 @@
+package collabFilter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func campingTransactions() [][]int {
+	// tent, stove, lantern, map
+	return [][]int{
+		{0, 1, 2},
+		{0, 1, 2},
+		{0, 1},
+		{0, 3},
+		{1, 2, 2},
+		{3}}
+}
+
+func TestFrequentItemsets(t *testing.T) {
+	transactions := campingTransactions()
+	itemsets, err := FrequentItemsets(transactions, 2.0/6)
+	Assert(t, err == nil)
+	found := make(map[string]int)
+	for _, itemset := range itemsets {
+		found[fmt.Sprint(itemset.Items)] = itemset.Count
+		Assert(t, itemset.Support == float64(itemset.Count)/6)
+	}
+	// same as counting every subset of the 4 products
+	for subset := 1; subset < 16; subset++ {
+		items := make([]int, 0)
+		for i := 0; i < 4; i++ {
+			if subset&(1<<uint(i)) != 0 {
+				items = append(items, i)
+			}
+		}
+		count := 0
+		for _, transaction := range transactions {
+			has := make(map[int]bool)
+			for _, item := range transaction {
+				has[item] = true
+			}
+			all := true
+			for _, item := range items {
+				all = all && has[item]
+			}
+			if all {
+				count++
+			}
+		}
+		mined, ok := found[fmt.Sprint(items)]
+		Assert(t, ok == (count >= 2), items, count)
+		Assert(t, !ok || mined == count, items, mined, count)
+	}
+
+	_, err = FrequentItemsets(transactions, 0)
+	Assert(t, err != nil)
+	_, err = FrequentItemsets([][]int{{-1}}, 0.5)
+	Assert(t, err != nil)
+}
+
+func TestMineRules(t *testing.T) {
+	rules, err := MineRules(campingTransactions(), 2.0/6, 0.6, 1)
+	Assert(t, err == nil && len(rules) > 0)
+	products := []string{"tent", "stove", "lantern", "map"}
+	var tent_stove Rule
+	for idx, rule := range rules {
+		Assert(t, rule.Confidence >= 0.6 && rule.Lift >= 1)
+		if idx > 0 {
+			Assert(t, rule.Confidence <= rules[idx-1].Confidence)
+		}
+		if rule.Format(products) == "{tent, stove} -> lantern" {
+			tent_stove = rule
+		}
+	}
+	// {tent, stove, lantern} in 2 of the 3 transactions with {tent, stove}, lantern in 3 of 6
+	Assert(t, tent_stove.Consequent == 2, rules)
+	Assert(t, math.Abs(tent_stove.Confidence-2.0/3) < 1e-9 && math.Abs(tent_stove.Lift-4.0/3) < 1e-9, tent_stove)
+	Assert(t, tent_stove.Support == 2.0/6)
+}
+
+func TestAssociationRules(t *testing.T) {
+	model, err := NewAssociationRules(campingTransactions(), 2.0/6, 0.5, 1)
+	Assert(t, err == nil)
+	products := []string{"tent", "stove", "lantern", "map"}
+	prods, scores, rules := model.Recommend([]int{0, 1}, products)
+	Assert(t, len(prods) == 1 && prods[0] == "lantern", prods)
+	// stove -> lantern is more confident than {tent, stove} -> lantern
+	Assert(t, scores[0] == 0.75 && rules[0].Format(products) == "{stove} -> lantern", scores, rules)
+
+	prods, _, rules = model.Recommend([]int{3}, products)
+	Assert(t, len(prods) == 0 && len(rules) == 0, prods)
+}
@@ -0,0 +1,75 @@
+package collabFilter
+
+import (
+	"math"
+	"sort"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// the matrix with each row divided by its sum and raised to the power alpha
+func transitions(M *DenseMatrix, alpha float64) *DenseMatrix {
+	P := Zeros(M.Rows(), M.Cols())
+	for r := 0; r < M.Rows(); r++ {
+		sum := float64(0)
+		for c := 0; c < M.Cols(); c++ {
+			sum += M.Get(r, c)
+		}
+		if sum == 0 {
+			continue
+		}
+		for c := 0; c < M.Cols(); c++ {
+			if val := M.Get(r, c); val != 0 {
+				P.Set(r, c, math.Pow(val/sum, alpha))
+			}
+		}
+	}
+	return P
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing), the exponent alpha of the transition
+// probabilities and the number of weights to keep per product (0 keeps all of them).
+// P3alpha, from Cooper et al. "Random Walks in Recommender Systems: Exact Computation and Simulations"
+// (WWW 2014): W(i, j) is the probability of a random walk on the user-product graph going from product i
+// to product j through a user, with every transition probability raised to the power alpha.
+// A user's score for product j is then sum_i r_ui * W(i, j), i.e. the 3-step walk starting from the user.
+func NewP3Alpha(prefs *DenseMatrix, alpha float64, k int) *LinearItemModel {
+	return NewRP3Beta(prefs, alpha, 0, k)
+}
+
+// Same as NewP3Alpha, but W(i, j) is divided by the popularity (number of users) of product j to the
+// power beta, as in Paudel et al. "Updatable, Accurate, Diverse, and Scalable Recommendations for
+// Interactive Applications" (TiiS 2016), so that popular products don't get recommended to everyone.
+// beta = 0 is P3alpha. Only the k largest weights of each row of W (each product i) are kept.
+func NewRP3Beta(prefs *DenseMatrix, alpha, beta float64, k int) *LinearItemModel {
+	prefs = replaceNA(prefs)
+	n_items := prefs.Cols()
+	// user -> product and product -> user transition probabilities
+	Pui := transitions(prefs, alpha)
+	Piu := transitions(prefs.Transpose(), alpha)
+	W, err := Piu.TimesDense(Pui)
+	errcheck(err)
+	for j := 0; j < n_items; j++ {
+		popularity := float64(0)
+		for u := 0; u < prefs.Rows(); u++ {
+			if prefs.Get(u, j) != 0 {
+				popularity++
+			}
+		}
+		if popularity > 0 && beta != 0 {
+			for i := 0; i < n_items; i++ {
+				W.Set(i, j, W.Get(i, j)/math.Pow(popularity, beta))
+			}
+		}
+	}
+	model := &LinearItemModel{Weights: make([][]Neighbor, n_items), prefs: prefs}
+	for i := 0; i < n_items; i++ {
+		for _, n := range topWeights(W.RowCopy(i), i, k) {
+			model.Weights[n.Index] = append(model.Weights[n.Index], Neighbor{i, n.Similarity})
+		}
+	}
+	for j := 0; j < n_items; j++ {
+		sort.Sort(bySimilarity(model.Weights[j]))
+	}
+	return model
+}
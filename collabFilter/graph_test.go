@@ -0,0 +1,52 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func TestP3Alpha(t *testing.T) {
+	prefs := testInteractions()
+	model := NewP3Alpha(prefs, 1, 0)
+	// from product 0, a walk goes to users 0, 1, 3, 5, each with probability 1/4, then to one of
+	// their products: product 2 is reached from users 0, 1 and 3, who have 3 products each
+	Assert(t, len(model.Weights[2]) > 0 && model.Weights[2][0].Index == 0, model.Weights[2])
+	Assert(t, math.Abs(model.Weights[2][0].Similarity-0.25) < 1e-9, model.Weights[2][0])
+	// the probabilities of leaving product 0 sum to 1, less the walks coming back to it
+	total := float64(0)
+	for j := range model.Weights {
+		for _, n := range model.Weights[j] {
+			if n.Index == 0 {
+				total += n.Similarity
+			}
+		}
+	}
+	back := 0.25 * (1.0/3 + 1.0/3 + 1.0/3 + 1.0/2)
+	Assert(t, math.Abs(total+back-1) < 1e-9, total)
+
+	prods, _, err := model.GetRecommendations(5, nil)
+	Assert(t, err == nil && len(prods) == 3 && prods[0] == "2", prods)
+}
+
+func TestRP3Beta(t *testing.T) {
+	prefs := testInteractions()
+	p3 := NewP3Alpha(prefs, 1, 0)
+	rp3 := NewRP3Beta(prefs, 1, 0.5, 0)
+	// products 2 and 3 are both bought by 3 users
+	Assert(t, math.Abs(rp3.Weights[2][0].Similarity-p3.Weights[2][0].Similarity/math.Sqrt(3)) < 1e-9)
+
+	// keep the 2 largest weights of each product
+	sparse := NewRP3Beta(prefs, 1, 0.5, 2)
+	from := make(map[int]int)
+	for j := range sparse.Weights {
+		for _, n := range sparse.Weights[j] {
+			from[n.Index]++
+		}
+	}
+	for i := 0; i < 5; i++ {
+		Assert(t, from[i] <= 2, from)
+	}
+	assertOwnCopy(t, func(prefs *DenseMatrix) Recommender { return NewRP3Beta(prefs, 1, 0.5, 0) })
+}
@@ -0,0 +1,135 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// a neighbor (user or item index) together with its similarity
+type Neighbor struct {
+	Index      int
+	Similarity float64
+}
+
+// sorts neighbors by descending similarity
+type bySimilarity []Neighbor
+
+func (n bySimilarity) Len() int      { return len(n) }
+func (n bySimilarity) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n bySimilarity) Less(i, j int) bool {
+	if n[i].Similarity == n[j].Similarity {
+		return n[i].Index < n[j].Index
+	}
+	return n[i].Similarity > n[j].Similarity
+}
+
+// Ranks the scores of products (index -> score) in descending order. Unlike sortMap, products with
+// equal scores are all kept, in order of their index.
+func rank(scores map[int]float64, products []string) ([]string, []float64) {
+	ranked := make([]Neighbor, 0, len(scores))
+	for idx, score := range scores {
+		ranked = append(ranked, Neighbor{idx, score})
+	}
+	sort.Sort(bySimilarity(ranked))
+	prods := make([]string, len(ranked))
+	vals := make([]float64, len(ranked))
+	for i, n := range ranked {
+		if products != nil {
+			prods[i] = products[n.Index]
+		} else {
+			prods[i] = strconv.Itoa(n.Index)
+		}
+		vals[i] = n.Similarity
+	}
+	return prods, vals
+}
+
+// Item-based nearest-neighbor collaborative filtering, as outlined in Sarwar et al.
+// "Item-Based Collaborative Filtering Recommendation Algorithms" (WWW 2001).
+// Item-item similarities are computed once, and a user's rating of an item is predicted from the
+// ratings the user gave to the most similar items.
+type ItemBased struct {
+	prefs *DenseMatrix
+	// similarities between all products (products x products)
+	Similarities *DenseMatrix
+	// the k most similar products of each product, most similar first
+	Neighbors [][]Neighbor
+}
+
+// Params: the prefs matrix, the number of neighbors to keep per product (0 keeps all of them) and the
+// similarity to compare two product columns with, e.g. CosineSim.
+// Returns the model with the precomputed product similarities.
+func NewItemBased(prefs *DenseMatrix, k int, similarity Similarity) *ItemBased {
+	prefs = replaceNA(prefs)
+	n_items := prefs.Cols()
+	model := &ItemBased{prefs: prefs, Similarities: Zeros(n_items, n_items), Neighbors: make([][]Neighbor, n_items)}
+	cols := make([][]float64, n_items)
+	for i := 0; i < n_items; i++ {
+		cols[i] = prefs.ColCopy(i)
+	}
+	for i := 0; i < n_items; i++ {
+		for j := i + 1; j < n_items; j++ {
+			sim := similarity(cols[i], cols[j])
+			if math.IsNaN(sim) {
+				sim = 0
+			}
+			model.Similarities.Set(i, j, sim)
+			model.Similarities.Set(j, i, sim)
+		}
+	}
+	for i := 0; i < n_items; i++ {
+		neighbors := make([]Neighbor, 0, n_items-1)
+		for j := 0; j < n_items; j++ {
+			if j != i {
+				neighbors = append(neighbors, Neighbor{j, model.Similarities.Get(i, j)})
+			}
+		}
+		sort.Sort(bySimilarity(neighbors))
+		if k > 0 && k < len(neighbors) {
+			neighbors = neighbors[:k]
+		}
+		model.Neighbors[i] = neighbors
+	}
+	return model
+}
+
+// weighted mean of the user's ratings of the neighbors of item. ok is false if the user
+// hasn't rated any of them.
+func (m *ItemBased) predict(user, item int) (pred float64, ok bool) {
+	return itemNeighborsMean(m.prefs, user, m.Neighbors[item])
+}
+
+// Returns the predicted rating of a user for a product. Error if out of range, or if the user
+// hasn't rated any of the product's neighbors.
+func (m *ItemBased) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	pred, ok := m.predict(user, item)
+	if !ok {
+		return 0, errors.New("user has not rated any neighbor of the product")
+	}
+	return pred, nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, using the
+// precomputed product neighbors. Returns products and predicted ratings in descending order.
+func (m *ItemBased) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			if pred, ok := m.predict(user, i); ok {
+				scores[i] = pred
+			}
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
@@ -0,0 +1,48 @@
+package collabFilter
+
+import (
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func TestRank(t *testing.T) {
+	scores := map[int]float64{0: 0.5, 1: 0.9, 2: 0.5}
+	names, vals := rank(scores, []string{"Drew", "Tim", "Kyle"})
+	// equal scores are kept, in order of index
+	Assert(t, len(names) == 3, names)
+	Assert(t, names[0] == "Tim" && names[1] == "Drew" && names[2] == "Kyle", names)
+	Assert(t, vals[0] == 0.9 && vals[2] == 0.5, vals)
+	names, _ = rank(scores, nil)
+	Assert(t, names[0] == "1", names)
+}
+
+func TestItemBased(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		2, 3, 4, 1, 5,
+		3, 0, 3, 3, 0,
+		4, 4, 1, 2, 3,
+		2, 4, 0, 3, 4,
+		3, 1, 3, 0, 4}, 5, 5)
+	products := []string{"Spiderman", "Big Momma's House", "Vanilla Sky", "Pacific Rim", "The Mask"}
+
+	model := NewItemBased(prefs, 2, CosineSim)
+	Assert(t, len(model.Neighbors[0]) == 2)
+	assertOwnCopy(t, func(prefs *DenseMatrix) Recommender { return NewItemBased(prefs, 2, CosineSim) })
+	Assert(t, model.Similarities.Get(0, 1) == model.Similarities.Get(1, 0))
+	Assert(t, model.Neighbors[0][0].Similarity >= model.Neighbors[0][1].Similarity)
+
+	prods, scores, err := model.GetRecommendations(1, products)
+	Assert(t, err == nil)
+	Assert(t, len(prods) == 2, prods)
+	Assert(t, prods[0] != "Spiderman" && prods[1] != "Spiderman", prods)
+	// user 1 only gave out 3's
+	Assert(t, scores[0] == 3 && scores[1] == 3, scores)
+
+	pred, err := model.Predict(4, 3)
+	Assert(t, err == nil && pred >= 1 && pred <= 5, pred)
+	_, err = model.Predict(5, 0)
+	Assert(t, err != nil)
+	_, _, err = model.GetRecommendations(5, products)
+	Assert(t, err != nil)
+}
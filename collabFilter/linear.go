@@ -0,0 +1,158 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// A learned, sparse item-item weight matrix W: the score of a user for product j is sum_i r_ui * W(i, j).
+// Made by TrainEASE, TrainSLIM, NewP3Alpha and NewRP3Beta. Ratings can be binary (bought/clicked) or
+// weighted (counts, ratings).
+type LinearItemModel struct {
+	// the non-zero weights W(i, j) of each product j, largest first
+	Weights [][]Neighbor
+
+	prefs *DenseMatrix
+}
+
+// the largest non-zero weights of a column (or row) of W, leaving out the product itself. k = 0 keeps all of them.
+func topWeights(col []float64, item, k int) []Neighbor {
+	weights := make([]Neighbor, 0)
+	for i, w := range col {
+		if i != item && w != 0 {
+			weights = append(weights, Neighbor{i, w})
+		}
+	}
+	sort.Sort(bySimilarity(weights))
+	if k > 0 && k < len(weights) {
+		weights = weights[:k]
+	}
+	return weights
+}
+
+// the item Gram matrix X^T X
+func gram(prefs *DenseMatrix) *DenseMatrix {
+	G, err := prefs.Transpose().TimesDense(prefs)
+	errcheck(err)
+	return G
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing), the L2 regularization and the number of weights
+// to keep per product (0 keeps all of them).
+// EASE^R, from Steck "Embarrassingly Shallow Autoencoders for Sparse Data" (WWW 2019): the ridge regression
+// of each product on all the others, with a zero diagonal, has the closed form W = I - P / diag(P),
+// P = (X^T X + lambda I)^-1. Error if the matrix can't be inverted.
+func TrainEASE(prefs *DenseMatrix, lambda float64, k int) (*LinearItemModel, error) {
+	prefs = replaceNA(prefs)
+	n_items := prefs.Cols()
+	G := gram(prefs)
+	for i := 0; i < n_items; i++ {
+		G.Set(i, i, G.Get(i, i)+lambda)
+	}
+	P, err := G.Inverse()
+	if err != nil {
+		return nil, err
+	}
+	model := &LinearItemModel{Weights: make([][]Neighbor, n_items), prefs: prefs}
+	for j := 0; j < n_items; j++ {
+		col := make([]float64, n_items)
+		for i := 0; i < n_items; i++ {
+			if i != j {
+				col[i] = -P.Get(i, j) / P.Get(j, j)
+			}
+		}
+		model.Weights[j] = topWeights(col, j, k)
+	}
+	return model, nil
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing), the L1 and L2 regularization, number of
+// iterations and the number of weights to keep per product (0 keeps all of them).
+// SLIM, from Ning and Karypis "SLIM: Sparse Linear Methods for Top-N Recommender Systems" (ICDM 2011):
+// each column of W solves the elastic net 1/2 ||x_j - X w||^2 + l1 |w|_1 + l2/2 |w|^2, with w >= 0 and
+// w_j = 0, by coordinate descent. The L1 term makes W sparse. Columns are learned in parallel.
+// Error if a regularization is negative.
+func TrainSLIM(prefs *DenseMatrix, l1, l2 float64, iterations, k int) (*LinearItemModel, error) {
+	if l1 < 0 || l2 < 0 {
+		return nil, errors.New("regularization can't be negative")
+	}
+	prefs = replaceNA(prefs)
+	n_items := prefs.Cols()
+	// with the Gram matrix, x_i^T (x_j - X w) = G(i, j) - (G w)_i
+	G := gram(prefs)
+	model := &LinearItemModel{Weights: make([][]Neighbor, n_items), prefs: prefs}
+
+	cols := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range cols {
+				w := make([]float64, n_items)
+				Gw := make([]float64, n_items)
+				for ii := 0; ii < iterations; ii++ {
+					for i := 0; i < n_items; i++ {
+						if i == j || G.Get(i, i) == 0 {
+							continue
+						}
+						// the least squares solution for w_i with the others fixed, soft-thresholded
+						rho := G.Get(i, j) - Gw[i] + G.Get(i, i)*w[i]
+						new_w := math.Max(rho-l1, 0) / (G.Get(i, i) + l2)
+						if delta := new_w - w[i]; delta != 0 {
+							for x := 0; x < n_items; x++ {
+								Gw[x] += G.Get(x, i) * delta
+							}
+							w[i] = new_w
+						}
+					}
+				}
+				model.Weights[j] = topWeights(w, j, k)
+			}
+		}()
+	}
+	for j := 0; j < n_items; j++ {
+		cols <- j
+	}
+	close(cols)
+	wg.Wait()
+	logger.Debug("SLIM trained", "products", n_items, "iterations", iterations)
+	return model, nil
+}
+
+func (m *LinearItemModel) score(user, item int) float64 {
+	score := float64(0)
+	for _, n := range m.Weights[item] {
+		score += m.prefs.Get(user, n.Index) * n.Similarity
+	}
+	return score
+}
+
+// Returns the score of a user for a product, sum_i r_ui * W(i, j). Error if out of range.
+func (m *LinearItemModel) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	return m.score(user, item), nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated. Returns products and
+// scores in descending order; the first n are the top-N recommendations.
+func (m *LinearItemModel) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			scores[i] = m.score(user, i)
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
@@ -0,0 +1,90 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func testInteractions() *DenseMatrix {
+	// products 0 and 2 always go together
+	return MakeRatingMatrix([]float64{
+		1, 1, 1, 0, 0,
+		1, 0, 1, 1, 0,
+		0, 1, 0, 1, 1,
+		1, 0, 1, 0, 1,
+		0, 0, 0, 1, 1,
+		1, 1, 0, 0, 0}, 6, 5)
+}
+
+func TestEASE(t *testing.T) {
+	prefs := testInteractions()
+	lambda := 0.5
+	model, err := TrainEASE(prefs, lambda, 0)
+	Assert(t, err == nil)
+	W := Zeros(5, 5)
+	for j, weights := range model.Weights {
+		for _, n := range weights {
+			Assert(t, n.Index != j)
+			W.Set(n.Index, j, n.Similarity)
+		}
+	}
+	// off the diagonal, (X^T X + lambda I) W = X^T X
+	G := gram(prefs)
+	for i := 0; i < 5; i++ {
+		G.Set(i, i, G.Get(i, i)+lambda)
+	}
+	GW, _ := G.TimesDense(W)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			if i != j {
+				Assert(t, math.Abs(GW.Get(i, j)-gram(prefs).Get(i, j)) < 1e-9, i, j)
+			}
+		}
+	}
+	// user 5 bought product 0, so product 2 comes first
+	prods, _, err := model.GetRecommendations(5, nil)
+	Assert(t, err == nil && len(prods) == 3 && prods[0] == "2", prods)
+
+	sparse, _ := TrainEASE(prefs, lambda, 2)
+	Assert(t, len(sparse.Weights[0]) == 2 && sparse.Weights[0][0] == model.Weights[0][0])
+	assertOwnCopy(t, func(prefs *DenseMatrix) Recommender {
+		model, _ := TrainEASE(prefs, lambda, 0)
+		return model
+	})
+}
+
+func TestSLIM(t *testing.T) {
+	prefs := testInteractions()
+	model, err := TrainSLIM(prefs, 0.1, 0.1, 50, 0)
+	Assert(t, err == nil)
+	assertOwnCopy(t, func(prefs *DenseMatrix) Recommender {
+		model, _ := TrainSLIM(prefs, 0.1, 0.1, 50, 0)
+		return model
+	})
+	for j, weights := range model.Weights {
+		for _, n := range weights {
+			Assert(t, n.Index != j && n.Similarity > 0)
+		}
+	}
+	Assert(t, model.Weights[2][0].Index == 0, model.Weights[2])
+	prods, scores, err := model.GetRecommendations(5, nil)
+	Assert(t, err == nil && prods[0] == "2", prods, scores)
+	pred, err := model.Predict(5, 2)
+	Assert(t, err == nil && pred == scores[0])
+
+	// more L1, fewer weights
+	count := func(m *LinearItemModel) int {
+		n := 0
+		for _, weights := range m.Weights {
+			n += len(weights)
+		}
+		return n
+	}
+	sparse, _ := TrainSLIM(prefs, 1, 0.1, 50, 0)
+	Assert(t, count(sparse) < count(model), count(sparse), count(model))
+
+	_, err = TrainSLIM(prefs, -1, 0, 10, 0)
+	Assert(t, err != nil)
+}
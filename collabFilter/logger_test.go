@@ -0,0 +1,26 @@
+package collabFilter
+
+import (
+	"errors"
+	"testing"
+)
+
+// keeps the messages logged at each level
+type recordingLogger map[string][]string
+
+func (r recordingLogger) Debug(msg string, args ...interface{}) { r["debug"] = append(r["debug"], msg) }
+func (r recordingLogger) Info(msg string, args ...interface{})  { r["info"] = append(r["info"], msg) }
+func (r recordingLogger) Error(msg string, args ...interface{}) { r["error"] = append(r["error"], msg) }
+
+func TestLogger(t *testing.T) {
+	logs := recordingLogger{}
+	SetLogger(logs)
+	defer SetLogger(nil)
+	NewSimilarityModel(testPrefs(), false, 2, CosineSim)
+	Assert(t, len(logs["debug"]) == 1, logs)
+	// recommendations aren't logged
+	GetRecommendations(testPrefs(), 1, nil)
+	Assert(t, len(logs["debug"]) == 1, logs)
+	errcheck(errors.New("oops"))
+	Assert(t, len(logs["error"]) == 1, logs)
+}
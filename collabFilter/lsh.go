@@ -0,0 +1,146 @@
+package collabFilter
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// A source of candidate neighbors for a user, so that the recommendation functions only compare the user
+// with those candidates instead of with every other row of the prefs matrix.
+type CandidateSource interface {
+	Candidates(user int) []int
+}
+
+// Locality-sensitive hashing index over the users (rows) of a prefs matrix. Each user's signature is split
+// into bands, and users that agree on all the hashes of at least one band become candidate neighbors.
+// With b bands of r rows, two users of similarity s are candidates with probability 1 - (1 - s^r)^b.
+type LSHIndex struct {
+	// a bucket per band key, for every band
+	buckets []map[uint64][]int
+	// the band keys of each user. nil for users without ratings.
+	keys [][]uint64
+}
+
+func newLSHIndex(signatures [][]uint64, bands, rows int) *LSHIndex {
+	index := &LSHIndex{buckets: make([]map[uint64][]int, bands), keys: make([][]uint64, len(signatures))}
+	for b := 0; b < bands; b++ {
+		index.buckets[b] = make(map[uint64][]int)
+	}
+	for user, signature := range signatures {
+		if signature == nil {
+			continue
+		}
+		index.keys[user] = make([]uint64, bands)
+		for b := 0; b < bands; b++ {
+			// FNV-1a over the hashes of the band
+			key := uint64(14695981039346656037)
+			for _, h := range signature[b*rows : (b+1)*rows] {
+				key ^= h
+				key *= 1099511628211
+			}
+			index.keys[user][b] = key
+			index.buckets[b][key] = append(index.buckets[b][key], user)
+		}
+	}
+	return index
+}
+
+// Returns the users sharing at least one band with user, in increasing order.
+func (idx *LSHIndex) Candidates(user int) []int {
+	if user < 0 || user >= len(idx.keys) {
+		return nil
+	}
+	seen := make(map[int]bool)
+	for b, key := range idx.keys[user] {
+		for _, other := range idx.buckets[b][key] {
+			if other != user {
+				seen[other] = true
+			}
+		}
+	}
+	candidates := make([]int, 0, len(seen))
+	for other := range seen {
+		candidates = append(candidates, other)
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// Builds a MinHash index for the Jaccard similarity of binary preferences: each user is the set of
+// products they have (non-zero entries). Params: the prefs matrix, number of bands, hashes per band, and
+// the seed of the hash functions.
+func NewMinHashIndex(prefs *DenseMatrix, bands, rows int, seed int64) *LSHIndex {
+	// universal hashing h(x) = (a*x + b) mod p, with p = 2^31 - 1
+	const p = uint64(1<<31 - 1)
+	r := rand.New(rand.NewSource(seed))
+	n_hashes := bands * rows
+	a := make([]uint64, n_hashes)
+	b := make([]uint64, n_hashes)
+	for k := 0; k < n_hashes; k++ {
+		a[k] = uint64(r.Int63n(int64(p-1))) + 1
+		b[k] = uint64(r.Int63n(int64(p)))
+	}
+	signatures := make([][]uint64, prefs.Rows())
+	for u := 0; u < prefs.Rows(); u++ {
+		var signature []uint64
+		for i := 0; i < prefs.Cols(); i++ {
+			val := prefs.Get(u, i)
+			if val == 0 || math.IsNaN(val) {
+				continue
+			}
+			if signature == nil {
+				signature = make([]uint64, n_hashes)
+				for k := range signature {
+					signature[k] = math.MaxUint64
+				}
+			}
+			for k := 0; k < n_hashes; k++ {
+				h := (a[k]*uint64(i+1) + b[k]) % p
+				if h < signature[k] {
+					signature[k] = h
+				}
+			}
+		}
+		signatures[u] = signature
+	}
+	return newLSHIndex(signatures, bands, rows)
+}
+
+// Builds a SimHash (random hyperplane) index for the cosine similarity of ratings: each hash is the side
+// of a random hyperplane a user's rating vector falls on. Params: the prefs matrix, number of bands,
+// hashes per band, and the seed of the hyperplanes.
+func NewSimHashIndex(prefs *DenseMatrix, bands, rows int, seed int64) *LSHIndex {
+	r := rand.New(rand.NewSource(seed))
+	n_hashes := bands * rows
+	planes := make([][]float64, n_hashes)
+	for k := 0; k < n_hashes; k++ {
+		planes[k] = make([]float64, prefs.Cols())
+		for i := range planes[k] {
+			planes[k][i] = r.NormFloat64()
+		}
+	}
+	signatures := make([][]uint64, prefs.Rows())
+	for u := 0; u < prefs.Rows(); u++ {
+		ratings := prefs.RowCopy(u)
+		for i, val := range ratings {
+			if math.IsNaN(val) {
+				ratings[i] = 0
+			}
+		}
+		if NormSquared(ratings) == 0 {
+			continue
+		}
+		signatures[u] = make([]uint64, n_hashes)
+		for k := 0; k < n_hashes; k++ {
+			dp, err := DotProduct(planes[k], ratings)
+			errcheck(err)
+			if dp >= 0 {
+				signatures[u][k] = 1
+			}
+		}
+	}
+	return newLSHIndex(signatures, bands, rows)
+}
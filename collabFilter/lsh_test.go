@@ -0,0 +1,95 @@
+package collabFilter
+
+import (
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// users 0-3 rate products 0-4, users 4-7 rate products 5-9. Users 0 and 4 haven't rated
+// products 1 and 6 yet.
+func clusteredPrefs() *DenseMatrix {
+	data := make([]float64, 0, 80)
+	for u := 0; u < 8; u++ {
+		for i := 0; i < 10; i++ {
+			if (u < 4) == (i < 5) && !(u == 0 && i == 1) && !(u == 4 && i == 6) {
+				data = append(data, float64(3+(u*i)%3))
+			} else {
+				data = append(data, 0)
+			}
+		}
+	}
+	return MakeRatingMatrix(data, 8, 10)
+}
+
+func binarize(prefs *DenseMatrix) *DenseMatrix {
+	binary := prefs.Copy()
+	arr := binary.Array()
+	for i := range arr {
+		if arr[i] != 0 {
+			arr[i] = 1
+		}
+	}
+	return binary
+}
+
+func TestMinHashIndex(t *testing.T) {
+	prefs := binarize(clusteredPrefs())
+	index := NewMinHashIndex(prefs, 8, 2, 47)
+	for u := 0; u < 8; u++ {
+		candidates := index.Candidates(u)
+		Assert(t, len(candidates) > 0, u)
+		for _, other := range candidates {
+			// the clusters have nothing in common
+			Assert(t, other != u && (other < 4) == (u < 4), u, candidates)
+		}
+	}
+	// identical sets always share every band
+	twins := MakeRatingMatrix([]float64{1, 0, 1, 1, 0, 1, 0, 1, 0}, 3, 3)
+	index = NewMinHashIndex(twins, 4, 3, 1)
+	Assert(t, len(index.Candidates(0)) == 1 && index.Candidates(0)[0] == 1, index.Candidates(0))
+	Assert(t, index.Candidates(3) == nil)
+}
+
+func TestSimHashIndex(t *testing.T) {
+	prefs := clusteredPrefs()
+	index := NewSimHashIndex(prefs, 20, 16, 47)
+	for u := 0; u < 8; u++ {
+		candidates := index.Candidates(u)
+		Assert(t, len(candidates) > 0, u)
+		for _, other := range candidates {
+			Assert(t, (other < 4) == (u < 4), u, candidates)
+		}
+	}
+	// a user without ratings has no candidates
+	empty := MakeRatingMatrix([]float64{1, 2, 0, 0, 2, 1}, 3, 2)
+	Assert(t, len(NewSimHashIndex(empty, 2, 2, 1).Candidates(1)) == 0)
+}
+
+func TestCandidateRecommendations(t *testing.T) {
+	prefs := clusteredPrefs()
+	simhash := NewSimHashIndex(prefs, 20, 16, 47)
+	prods, _, err := GetRecommendationsWithOptions(prefs, 0, nil, Options{Candidates: simhash})
+	// neighbors only come from user 0's cluster, so only its missing product is recommended
+	Assert(t, err == nil && len(prods) == 1 && prods[0] == "1", prods)
+
+	binary := binarize(prefs)
+	minhash := NewMinHashIndex(binary, 8, 2, 47)
+	prods, scores, err := GetBinaryRecommendationsWithOptions(binary, 0, nil, Options{Candidates: minhash})
+	Assert(t, err == nil && prods[0] == "1" && scores[0] > 0, prods, scores)
+
+	// a precomputed user model works as a candidate source too
+	users := NewSimilarityModel(prefs, false, 2, CosineSim)
+	Assert(t, len(users.Candidates(0)) == 2)
+	_, _, err = GetRecommendationsWithOptions(prefs, 0, nil, Options{Candidates: users})
+	Assert(t, err == nil)
+
+	// only the candidates are compared with
+	compared := 0
+	counting := func(a, b []float64) float64 {
+		compared++
+		return CosineSim(a, b)
+	}
+	_, _, err = GetRecommendationsWithOptions(prefs, 0, nil, Options{Similarity: counting, Candidates: users, Normalization: ZScore})
+	Assert(t, err == nil && compared == 2, compared)
+}
@@ -0,0 +1,338 @@
+package collabFilter
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// A similarity between two rating vectors (rows or columns of the prefs matrix), where 0 means not rated.
+// CosineSim, Jaccard, Pearson etc. are all Similarities, and any other metric can be plugged in.
+type Similarity func(a, b []float64) float64
+
+var (
+	similaritiesMu sync.RWMutex
+	similarities   = map[string]Similarity{
+		"cosine":            CosineSim,
+		"jaccard":           Jaccard,
+		"weighted-jaccard":  WeightedJaccard,
+		"pearson":           Pearson,
+		"spearman":          Spearman,
+		"tanimoto":          Tanimoto,
+		"euclidean":         Euclidean,
+		"msd":               MeanSquaredDifference,
+		"asymmetric-cosine": AsymmetricCosine(0.5),
+	}
+)
+
+// Registers a similarity under name, so it can be chosen from configuration with GetSimilarity.
+// Registering an existing name replaces it.
+func RegisterSimilarity(name string, sim Similarity) {
+	similaritiesMu.Lock()
+	defer similaritiesMu.Unlock()
+	similarities[name] = sim
+}
+
+// Removes the similarity registered under name, if any.
+func UnregisterSimilarity(name string) {
+	similaritiesMu.Lock()
+	defer similaritiesMu.Unlock()
+	delete(similarities, name)
+}
+
+// Returns the similarity registered under name. Error if there is none.
+// AdjustedCosine depends on the data and has to be built with AdjustedCosine(means) instead.
+func GetSimilarity(name string) (Similarity, error) {
+	similaritiesMu.RLock()
+	defer similaritiesMu.RUnlock()
+	sim, ok := similarities[name]
+	if !ok {
+		return nil, errors.New("no similarity registered as " + name)
+	}
+	return sim, nil
+}
+
+// Returns the names of all registered similarities, in alphabetical order.
+func SimilarityNames() []string {
+	similaritiesMu.RLock()
+	defer similaritiesMu.RUnlock()
+	names := make([]string, 0, len(similarities))
+	for name := range similarities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// returns the entries of a and b at the positions both have rated (non-zero, non-NaN).
+func coRated(a, b []float64) (x, y []float64, positions []int) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != 0 && b[i] != 0 && !math.IsNaN(a[i]) && !math.IsNaN(b[i]) {
+			x = append(x, a[i])
+			y = append(y, b[i])
+			positions = append(positions, i)
+		}
+	}
+	return
+}
+
+func mean(x []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	return sum(x) / float64(len(x))
+}
+
+// sum(x*y) / sqrt(sum(x^2) * sum(y^2)), or 0 if either vector is all zero.
+func centeredCosine(x, y []float64) float64 {
+	dp, x2, y2 := float64(0), float64(0), float64(0)
+	for i := 0; i < len(x); i++ {
+		dp += x[i] * y[i]
+		x2 += x[i] * x[i]
+		y2 += y[i] * y[i]
+	}
+	if x2 == 0 || y2 == 0 {
+		return 0
+	}
+	return dp / math.Sqrt(x2*y2)
+}
+
+func pearson(x, y []float64) float64 {
+	mx, my := mean(x), mean(y)
+	dx := make([]float64, len(x))
+	dy := make([]float64, len(y))
+	for i := 0; i < len(x); i++ {
+		dx[i] = x[i] - mx
+		dy[i] = y[i] - my
+	}
+	return centeredCosine(dx, dy)
+}
+
+// Pearson correlation between two rating vectors, computed over the co-rated items only
+// (0 or NaN means not rated). Returns a value from -1 to 1, and 0 with fewer than 2 co-rated items.
+func Pearson(a, b []float64) float64 {
+	x, y, _ := coRated(a, b)
+	if len(x) < 2 {
+		return 0
+	}
+	return pearson(x, y)
+}
+
+// returns the rank of each value, starting at 1. Tied values get the average of their ranks.
+func ranks(x []float64) []float64 {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return x[order[i]] < x[order[j]] })
+	r := make([]float64, len(x))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && x[order[j+1]] == x[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			r[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return r
+}
+
+// Spearman rank correlation between two rating vectors: the Pearson correlation of the ranks
+// of the co-rated items. Returns a value from -1 to 1, and 0 with fewer than 2 co-rated items.
+func Spearman(a, b []float64) float64 {
+	x, y, _ := coRated(a, b)
+	if len(x) < 2 {
+		return 0
+	}
+	return pearson(ranks(x), ranks(y))
+}
+
+// Adjusted cosine similarity (Sarwar et al.): cosine similarity over the co-rated positions after
+// subtracting means[k] from the k-th entry of both vectors. Use ItemMeans when comparing users
+// (rows), and UserMeans when comparing items (columns).
+func AdjustedCosine(means []float64) Similarity {
+	return func(a, b []float64) float64 {
+		x, y, positions := coRated(a, b)
+		for i, k := range positions {
+			x[i] -= means[k]
+			y[i] -= means[k]
+		}
+		return centeredCosine(x, y)
+	}
+}
+
+// rated returns the non-zero, non-NaN entries of x.
+func rated(x []float64) []float64 {
+	vals := make([]float64, 0, len(x))
+	for _, val := range x {
+		if val != 0 && !math.IsNaN(val) {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
+
+// Mean rating of each product (column), over the users who rated it.
+func ItemMeans(prefs *DenseMatrix) []float64 {
+	means := make([]float64, prefs.Cols())
+	for i := 0; i < prefs.Cols(); i++ {
+		means[i] = mean(rated(prefs.ColCopy(i)))
+	}
+	return means
+}
+
+// Mean rating of each user (row), over the products they rated.
+func UserMeans(prefs *DenseMatrix) []float64 {
+	means := make([]float64, prefs.Rows())
+	for u := 0; u < prefs.Rows(); u++ {
+		means[u] = mean(rated(prefs.RowCopy(u)))
+	}
+	return means
+}
+
+// Tanimoto coefficient (extended Jaccard): a.b / (|a|^2 + |b|^2 - a.b). Equal to Jaccard for binary vectors.
+// NaN if the vectors have different lengths.
+func Tanimoto(a, b []float64) float64 {
+	dp := dotOrNaN(a, b)
+	denom := sum(squares(a)) + sum(squares(b)) - dp
+	if denom == 0 {
+		return 0
+	}
+	return dp / denom
+}
+
+func squares(x []float64) []float64 {
+	sq := make([]float64, len(x))
+	for i, val := range x {
+		if !math.IsNaN(val) {
+			sq[i] = val * val
+		}
+	}
+	return sq
+}
+
+// Similarity based on the Euclidean distance d over the co-rated items: 1 / (1 + d).
+// Returns 0 if there are no co-rated items.
+func Euclidean(a, b []float64) float64 {
+	x, y, _ := coRated(a, b)
+	if len(x) == 0 {
+		return 0
+	}
+	dist := float64(0)
+	for i := 0; i < len(x); i++ {
+		dist += (x[i] - y[i]) * (x[i] - y[i])
+	}
+	return 1 / (1 + math.Sqrt(dist))
+}
+
+// Mean squared difference similarity (Shardanand & Maes): 1 / (1 + msd), where msd is the mean squared
+// difference of the co-rated items. Returns 0 if there are no co-rated items.
+func MeanSquaredDifference(a, b []float64) float64 {
+	x, y, _ := coRated(a, b)
+	if len(x) == 0 {
+		return 0
+	}
+	msd := float64(0)
+	for i := 0; i < len(x); i++ {
+		msd += (x[i] - y[i]) * (x[i] - y[i])
+	}
+	return 1 / (1 + msd/float64(len(x)))
+}
+
+// Asymmetric cosine similarity (Aiolli, 2013): a.b / (|a|^(2*alpha) * |b|^(2*(1-alpha))).
+// An alpha of 0.5 is the cosine similarity; other values weigh how much of a is covered by b differently
+// from how much of b is covered by a, which helps with binary data. NaN if the vectors have different lengths.
+func AsymmetricCosine(alpha float64) Similarity {
+	return func(a, b []float64) float64 {
+		// checked first, as the denominator can be 0 anyway
+		if len(a) != len(b) {
+			return math.NaN()
+		}
+		dp := dotOrNaN(a, b)
+		denom := math.Pow(sum(squares(a)), alpha) * math.Pow(sum(squares(b)), 1-alpha)
+		if denom == 0 {
+			return 0
+		}
+		return dp / denom
+	}
+}
+
+// Significance weighting (Herlocker et al.): scales sim by min(n, N) / N, where n is the number of
+// co-rated items. Similarities based on fewer than N co-rated items are trusted proportionally less.
+// N <= 0 leaves sim as it is.
+func SignificanceWeighting(sim Similarity, N int) Similarity {
+	if N <= 0 {
+		return sim
+	}
+	return func(a, b []float64) float64 {
+		_, _, positions := coRated(a, b)
+		n := len(positions)
+		if n > N {
+			n = N
+		}
+		return sim(a, b) * float64(n) / float64(N)
+	}
+}
+
+// Shrinkage (Bell & Koren): scales sim by n / (n + lambda), where n is the number of co-rated items,
+// pulling similarities based on few co-rated items towards 0.
+func Shrinkage(sim Similarity, lambda float64) Similarity {
+	return func(a, b []float64) float64 {
+		_, _, positions := coRated(a, b)
+		n := float64(len(positions))
+		if n+lambda == 0 {
+			return 0
+		}
+		return sim(a, b) * n / (n + lambda)
+	}
+}
+
+// Jaccard similarity of two sparse sets of product IDs: |A n B| / |A u B|.
+// Duplicate IDs are ignored. Returns 0 if both sets are empty.
+func SetJaccard(a, b []int) float64 {
+	set_a := make(map[int]bool, len(a))
+	for _, id := range a {
+		set_a[id] = true
+	}
+	set_b := make(map[int]bool, len(b))
+	for _, id := range b {
+		set_b[id] = true
+	}
+	intersection := 0
+	for id := range set_b {
+		if set_a[id] {
+			intersection++
+		}
+	}
+	union := len(set_a) + len(set_b) - intersection
+	if union == 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// Weighted Jaccard similarity for counts (e.g. number of purchases): sum(min(a, b)) / sum(max(a, b)).
+// Equal to Jaccard for binary vectors. Returns 0 if both are empty.
+func WeightedJaccard(a, b []float64) float64 {
+	mins, maxs := float64(0), float64(0)
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[i], b[i]
+		if math.IsNaN(x) {
+			x = 0
+		}
+		if math.IsNaN(y) {
+			y = 0
+		}
+		mins += math.Min(x, y)
+		maxs += math.Max(x, y)
+	}
+	if maxs == 0 {
+		return 0
+	}
+	return mins / maxs
+}
@@ -0,0 +1,254 @@
+package collabFilter
+
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// Precomputed top-k neighbor lists for every user (row) or every product (column) of a prefs matrix,
+// so that recommendations don't need to compare all pairs on every request. The lists can be saved
+// to disk, and are kept up to date as new ratings arrive with Update.
+type SimilarityModel struct {
+	// number of neighbors kept per user/product (0 keeps all of them), and whether products
+	// rather than users are compared.
+	K      int
+	ByItem bool
+	// the neighbors of each user/product, most similar first
+	Neighbors [][]Neighbor
+
+	prefs      *DenseMatrix
+	similarity Similarity
+}
+
+// Params: the prefs matrix, whether to compare products (columns) instead of users (rows), the number of
+// neighbors to keep (0 keeps all of them) and the similarity. The neighbor lists are computed in parallel.
+// The model keeps its own copy of prefs, so Update doesn't change the caller's matrix.
+func NewSimilarityModel(prefs *DenseMatrix, byItem bool, k int, similarity Similarity) *SimilarityModel {
+	model := &SimilarityModel{K: k, ByItem: byItem, prefs: replaceNA(prefs), similarity: similarity}
+	n := model.size()
+	vectors := make([][]float64, n)
+	for x := 0; x < n; x++ {
+		vectors[x] = model.vector(x)
+	}
+	model.Neighbors = make([][]Neighbor, n)
+
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for x := range rows {
+				model.Neighbors[x] = model.neighbors(x, vectors)
+			}
+		}()
+	}
+	for x := 0; x < n; x++ {
+		rows <- x
+	}
+	close(rows)
+	wg.Wait()
+	logger.Debug("similarity model built", "size", n, "by_item", byItem, "k", k)
+	return model
+}
+
+// the number of users, or products if ByItem
+func (m *SimilarityModel) size() int {
+	if m.ByItem {
+		return m.prefs.Cols()
+	}
+	return m.prefs.Rows()
+}
+
+// the ratings of user x, or of product x if ByItem
+func (m *SimilarityModel) vector(x int) []float64 {
+	if m.ByItem {
+		return m.prefs.ColCopy(x)
+	}
+	return m.prefs.RowCopy(x)
+}
+
+func (m *SimilarityModel) sim(a, b []float64) float64 {
+	sim := m.similarity(a, b)
+	if math.IsNaN(sim) {
+		return 0
+	}
+	return sim
+}
+
+// computes the top-k neighbors of x from scratch
+func (m *SimilarityModel) neighbors(x int, vectors [][]float64) []Neighbor {
+	neighbors := make([]Neighbor, 0, len(vectors))
+	for y := 0; y < len(vectors); y++ {
+		if y != x {
+			neighbors = append(neighbors, Neighbor{y, m.sim(vectors[x], vectors[y])})
+		}
+	}
+	sort.Sort(bySimilarity(neighbors))
+	if m.K > 0 && m.K < len(neighbors) {
+		neighbors = neighbors[:m.K]
+	}
+	return neighbors
+}
+
+// Sets a user's rating of a product, and updates the neighbor lists affected by it: those of the changed
+// user (or product), and those that contain it or that it now belongs to. A list is only recomputed
+// from scratch when the changed user/product drops out of it. Error if out of range.
+func (m *SimilarityModel) Update(user, item int, rating float64) error {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return errors.New("user/product index out of range")
+	}
+	m.prefs.Set(user, item, rating)
+	changed := user
+	if m.ByItem {
+		changed = item
+	}
+	vectors := make([][]float64, m.size())
+	for x := 0; x < len(vectors); x++ {
+		vectors[x] = m.vector(x)
+	}
+	m.Neighbors[changed] = m.neighbors(changed, vectors)
+	for x := 0; x < len(vectors); x++ {
+		if x == changed {
+			continue
+		}
+		sim := m.sim(vectors[x], vectors[changed])
+		neighbors := m.Neighbors[x]
+		full := m.K > 0 && len(neighbors) >= m.K
+		pos := -1
+		for idx, n := range neighbors {
+			if n.Index == changed {
+				pos = idx
+			}
+		}
+		switch {
+		case pos >= 0 && (!full || sim >= neighbors[pos].Similarity):
+			neighbors[pos].Similarity = sim
+		case pos >= 0:
+			// the next best neighbor isn't known, so start over
+			m.Neighbors[x] = m.neighbors(x, vectors)
+			continue
+		case !full:
+			neighbors = append(neighbors, Neighbor{changed, sim})
+		case sim > neighbors[len(neighbors)-1].Similarity:
+			neighbors[len(neighbors)-1] = Neighbor{changed, sim}
+		default:
+			continue
+		}
+		sort.Sort(bySimilarity(neighbors))
+		m.Neighbors[x] = neighbors
+	}
+	return nil
+}
+
+// similarity weighted mean of the user's ratings of the given products. ok is false if the user
+// hasn't rated any of them.
+func itemNeighborsMean(prefs *DenseMatrix, user int, neighbors []Neighbor) (pred float64, ok bool) {
+	ratings, sims := float64(0), float64(0)
+	for _, n := range neighbors {
+		rating := prefs.Get(user, n.Index)
+		if rating != 0 && n.Similarity != 0 {
+			ratings += n.Similarity * rating
+			sims += math.Abs(n.Similarity)
+		}
+	}
+	if sims == 0 {
+		return 0, false
+	}
+	return ratings / sims, true
+}
+
+func (m *SimilarityModel) predict(user, item int) (float64, bool) {
+	if m.ByItem {
+		return itemNeighborsMean(m.prefs, user, m.Neighbors[item])
+	}
+	// the user's neighbors who rated the product
+	raters := make([]Neighbor, 0)
+	for _, n := range m.Neighbors[user] {
+		if m.prefs.Get(n.Index, item) != 0 {
+			raters = append(raters, n)
+		}
+	}
+	return weightedMean(m.prefs, user, item, raters, Options{}, ratingStats{})
+}
+
+// Returns the predicted rating of a user for a product from the precomputed neighbors. Error if out of
+// range, or if none of the neighbors can be used.
+func (m *SimilarityModel) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	pred, ok := m.predict(user, item)
+	if !ok {
+		return 0, errors.New("no neighbors to predict from")
+	}
+	return pred, nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated, using the
+// precomputed neighbors. Returns products and predicted ratings in descending order.
+func (m *SimilarityModel) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			if pred, ok := m.predict(user, i); ok {
+				scores[i] = pred
+			}
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
+
+// Returns the precomputed neighbors of a user, so that a user model (ByItem false) can be used as
+// the candidates of GetRecommendationsWithOptions. Returns nil for product models.
+func (m *SimilarityModel) Candidates(user int) []int {
+	if m.ByItem || user < 0 || user >= len(m.Neighbors) {
+		return nil
+	}
+	candidates := make([]int, len(m.Neighbors[user]))
+	for idx, n := range m.Neighbors[user] {
+		candidates[idx] = n.Index
+	}
+	return candidates
+}
+
+// Saves the neighbor lists to a file.
+func (m *SimilarityModel) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(m)
+}
+
+// Loads neighbor lists saved with Save. The prefs matrix and similarity they were computed with
+// are needed to make predictions and updates.
+func LoadSimilarityModel(path string, prefs *DenseMatrix, similarity Similarity) (*SimilarityModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	model := &SimilarityModel{}
+	if err := gob.NewDecoder(f).Decode(model); err != nil {
+		return nil, err
+	}
+	model.prefs = replaceNA(prefs)
+	model.similarity = similarity
+	if len(model.Neighbors) != model.size() {
+		return nil, errors.New("saved model doesn't match the prefs matrix")
+	}
+	return model, nil
+}
@@ -0,0 +1,104 @@
+package collabFilter
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+func testPrefs() *DenseMatrix {
+	return MakeRatingMatrix([]float64{
+		2, 3, 4, 1, 5,
+		3, 0, 3, 3, 0,
+		4, 4, 1, 2, 3,
+		2, 4, 0, 3, 4,
+		3, 1, 3, 0, 4}, 5, 5)
+}
+
+// checks that a model keeps its own copy of prefs: the caller's NaNs aren't rewritten, and changing the
+// caller's matrix afterwards doesn't change the recommendations.
+func assertOwnCopy(t *testing.T, build func(prefs *DenseMatrix) Recommender) {
+	prefs := testPrefs()
+	prefs.Set(1, 1, math.NaN())
+	model := build(prefs)
+	Assert(t, math.IsNaN(prefs.Get(1, 1)), prefs)
+	prods, scores, err := model.GetRecommendations(1, nil)
+	for i := 0; i < prefs.Cols(); i++ {
+		prefs.Set(1, i, 5)
+	}
+	after, after_scores, _ := model.GetRecommendations(1, nil)
+	Assert(t, err == nil && reflect.DeepEqual(prods, after) && reflect.DeepEqual(scores, after_scores), prods, after)
+}
+
+func TestSimilarityModel(t *testing.T) {
+	prefs := testPrefs()
+	users := NewSimilarityModel(prefs, false, 2, CosineSim)
+	Assert(t, len(users.Neighbors) == 5 && len(users.Neighbors[0]) == 2)
+	// the lists are the most similar users
+	sims := make(map[int]float64)
+	for _, n := range userSimilarities(prefs, 1, Options{}) {
+		sims[n.Index] = n.Similarity
+	}
+	for _, n := range users.Neighbors[1] {
+		Assert(t, n.Similarity == sims[n.Index], n, sims)
+	}
+	_, scores, err := users.GetRecommendations(1, nil)
+	Assert(t, err == nil && len(scores) > 0)
+
+	// the item model gives the same predictions as ItemBased
+	items := NewSimilarityModel(prefs, true, 2, CosineSim)
+	itemBased := NewItemBased(prefs, 2, CosineSim)
+	for i := 0; i < 5; i++ {
+		pred, err := items.Predict(4, i)
+		expected, expectedErr := itemBased.Predict(4, i)
+		Assert(t, pred == expected && (err == nil) == (expectedErr == nil), i, pred, expected)
+	}
+	_, err = items.Predict(5, 0)
+	Assert(t, err != nil)
+}
+
+func TestSimilarityModelUpdate(t *testing.T) {
+	for _, byItem := range []bool{false, true} {
+		prefs := testPrefs()
+		model := NewSimilarityModel(prefs, byItem, 2, CosineSim)
+		updated := testPrefs()
+		for _, r := range [][3]int{{1, 1, 5}, {1, 4, 1}, {3, 2, 5}, {0, 0, 1}} {
+			Assert(t, model.Update(r[0], r[1], float64(r[2])) == nil)
+			updated.Set(r[0], r[1], float64(r[2]))
+		}
+		// the caller's matrix is left alone
+		Assert(t, reflect.DeepEqual(prefs.Array(), testPrefs().Array()), prefs)
+		// incremental updates should give the same lists as starting over
+		expected := NewSimilarityModel(updated, byItem, 2, CosineSim)
+		for x := range expected.Neighbors {
+			for idx, n := range expected.Neighbors[x] {
+				got := model.Neighbors[x][idx]
+				Assert(t, got.Index == n.Index && math.Abs(got.Similarity-n.Similarity) < 1e-12, byItem, x, model.Neighbors[x], n)
+			}
+		}
+		Assert(t, model.Update(5, 0, 1) != nil)
+	}
+}
+
+func TestSimilarityModelSave(t *testing.T) {
+	prefs := testPrefs()
+	model := NewSimilarityModel(prefs, true, 3, Pearson)
+	path := filepath.Join(os.TempDir(), "similarity_model_test.gob")
+	defer os.Remove(path)
+	Assert(t, model.Save(path) == nil)
+
+	loaded, err := LoadSimilarityModel(path, prefs, Pearson)
+	Assert(t, err == nil, err)
+	Assert(t, loaded.K == 3 && loaded.ByItem && len(loaded.Neighbors) == 5)
+	Assert(t, loaded.Neighbors[2][0] == model.Neighbors[2][0])
+	pred, _ := model.Predict(1, 1)
+	loadedPred, _ := loaded.Predict(1, 1)
+	Assert(t, pred == loadedPred)
+
+	_, err = LoadSimilarityModel(path, MakeRatingMatrix([]float64{1, 2}, 1, 2), Pearson)
+	Assert(t, err != nil)
+}
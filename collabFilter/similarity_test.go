@@ -0,0 +1,164 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPearson(t *testing.T) {
+	x := []float64{1, 2, 3, 0, 5}
+	y := []float64{2, 4, 6, 5, 0}
+	// co-rated items are the first three, which are perfectly correlated
+	Assert(t, math.Abs(Pearson(x, y)-1) < 1e-9, Pearson(x, y))
+	Assert(t, math.Abs(Pearson(x, []float64{3, 2, 1, 0, 0})+1) < 1e-9)
+	// not enough co-rated items
+	Assert(t, Pearson([]float64{1, 0}, []float64{1, 1}) == 0)
+	// no variance
+	Assert(t, Pearson([]float64{3, 3, 3}, []float64{1, 2, 3}) == 0)
+}
+
+func TestSpearman(t *testing.T) {
+	x := []float64{1, 2, 3, 4}
+	y := []float64{1, 4, 9, 16}
+	// monotonic, so the ranks are perfectly correlated even though the values are not linear
+	Assert(t, math.Abs(Spearman(x, y)-1) < 1e-9, Spearman(x, y))
+	Assert(t, Pearson(x, y) < 1)
+
+	r := ranks([]float64{10, 20, 20, 5})
+	Assert(t, r[0] == 2 && r[1] == 3.5 && r[2] == 3.5 && r[3] == 1, r)
+}
+
+func TestAdjustedCosine(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		5, 1, 0,
+		4, 2, 3,
+		1, 5, 4}, 3, 3)
+	means := ItemMeans(prefs)
+	Assert(t, means[0] == 10.0/3 && means[2] == 3.5, means)
+	Assert(t, UserMeans(prefs)[1] == 3)
+
+	sim := AdjustedCosine(means)
+	// users 0 and 1 both like product 0 more than average, user 2 does not
+	Assert(t, sim(prefs.RowCopy(0), prefs.RowCopy(1)) > 0)
+	Assert(t, sim(prefs.RowCopy(0), prefs.RowCopy(2)) < 0)
+}
+
+func TestSelectableSimilarity(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		2, 3, 4, 1, 5,
+		3, 0, 3, 3, 0,
+		4, 4, 1, 2, 3,
+		2, 4, 0, 3, 4,
+		3, 1, 3, 0, 4}, 5, 5)
+
+	positive := float64(0)
+	for _, sim := range []Similarity{Pearson, Spearman, AdjustedCosine(ItemMeans(prefs))} {
+		// a mean of raw ratings only stays within the ratings with non-negative weights
+		_, scores, err := GetRecommendationsWithOptions(prefs, 3, nil, Options{Similarity: sim, MinSimilarity: &positive})
+		Assert(t, err == nil)
+		for _, score := range scores {
+			Assert(t, score >= 1 && score <= 5, scores)
+		}
+	}
+	model := NewItemBased(prefs, 0, AdjustedCosine(UserMeans(prefs)))
+	Assert(t, model.Similarities.Get(0, 1) >= -1 && model.Similarities.Get(0, 1) <= 1)
+}
+
+func TestSimilarityRegistry(t *testing.T) {
+	sim, err := GetSimilarity("pearson")
+	Assert(t, err == nil && sim([]float64{1, 2, 3}, []float64{1, 2, 3}) == Pearson([]float64{1, 2, 3}, []float64{1, 2, 3}))
+	_, err = GetSimilarity("nope")
+	Assert(t, err != nil)
+
+	// custom metrics can be plugged in by name
+	RegisterSimilarity("constant", func(a, b []float64) float64 { return 1 })
+	defer UnregisterSimilarity("constant")
+	sim, err = GetSimilarity("constant")
+	Assert(t, err == nil && sim(nil, nil) == 1)
+	names := SimilarityNames()
+	Assert(t, len(names) == 10 && names[0] == "asymmetric-cosine" && names[1] == "constant", names)
+
+	UnregisterSimilarity("constant")
+	_, err = GetSimilarity("constant")
+	Assert(t, err != nil && len(SimilarityNames()) == 9)
+}
+
+func TestMismatchedLengths(t *testing.T) {
+	// undefined, rather than a similarity of 0
+	a, b := []float64{1, 2, 3}, []float64{1, 2}
+	for _, sim := range []Similarity{CosineSim, Tanimoto, AsymmetricCosine(0.3)} {
+		Assert(t, math.IsNaN(sim(a, b)), sim(a, b))
+	}
+	Assert(t, math.IsNaN(AsymmetricCosine(0.3)([]float64{0, 0}, []float64{0})))
+}
+
+func TestOtherSimilarities(t *testing.T) {
+	x := []float64{1, 1, 0, 1, 1}
+	y := []float64{1, 1, 1, 0, 1}
+	// Tanimoto is Jaccard for binary data
+	Assert(t, Tanimoto(x, y) == 0.6, Tanimoto(x, y))
+	Assert(t, math.Abs(AsymmetricCosine(0.5)(x, y)-CosineSim(x, y)) < 1e-9)
+	// 3 co-rated of x's 4 items and y's 4 items
+	Assert(t, math.Abs(AsymmetricCosine(1)(x, y)-0.75) < 1e-9)
+
+	a := []float64{5, 3, 0, 1}
+	b := []float64{4, 3, 2, 0}
+	Assert(t, math.Abs(Euclidean(a, b)-0.5) < 1e-9, Euclidean(a, b))
+	Assert(t, math.Abs(MeanSquaredDifference(a, b)-1/1.5) < 1e-9, MeanSquaredDifference(a, b))
+	Assert(t, Euclidean(a, []float64{0, 0, 1, 0}) == 0)
+}
+
+func TestBinaryRecommendationsWithOptions(t *testing.T) {
+	binaryPrefs := MakeRatingMatrix([]float64{
+		1, 1, 1, 1, 0,
+		0, 1, 1, 0, 1,
+		1, 1, 1, 1, 1,
+		1, 1, 0, 0, 1,
+		1, 0, 1, 1, 1}, 5, 5)
+	products := []string{"Spiderman", "Big Momma's House", "Vanilla Sky", "Pacific Rim", "The Mask"}
+
+	prods, scores, err := GetBinaryRecommendationsWithOptions(binaryPrefs, 1, products, Options{})
+	expected, expectedScores, _ := GetBinaryRecommendations(binaryPrefs, 1, products)
+	Assert(t, err == nil && prods[0] == expected[0] && scores[0] == expectedScores[0])
+
+	sim, _ := GetSimilarity("tanimoto")
+	prods, _, err = GetBinaryRecommendationsWithOptions(binaryPrefs, 1, products, Options{Similarity: sim})
+	Assert(t, err == nil && len(prods) == 2, prods)
+}
+
+func TestSignificanceWeighting(t *testing.T) {
+	few := [][]float64{{5, 0, 0, 0}, {5, 0, 0, 0}}
+	many := [][]float64{{5, 3, 4, 1}, {5, 3, 4, 1}}
+	// identical on 1 co-rated item, or on 4 of them
+	Assert(t, math.Abs(CosineSim(few[0], few[1])-CosineSim(many[0], many[1])) < 1e-9)
+
+	weighted := SignificanceWeighting(CosineSim, 2)
+	Assert(t, math.Abs(weighted(few[0], few[1])-0.5) < 1e-9, weighted(few[0], few[1]))
+	Assert(t, math.Abs(weighted(many[0], many[1])-1) < 1e-9, weighted(many[0], many[1]))
+	// no weighting, rather than 0/0
+	for _, N := range []int{0, -1} {
+		Assert(t, SignificanceWeighting(CosineSim, N)(few[0], few[1]) == CosineSim(few[0], few[1]), N)
+	}
+
+	shrunk := Shrinkage(CosineSim, 4)
+	Assert(t, math.Abs(shrunk(few[0], few[1])-0.2) < 1e-9, shrunk(few[0], few[1]))
+	Assert(t, math.Abs(shrunk(many[0], many[1])-0.5) < 1e-9, shrunk(many[0], many[1]))
+
+	// can be applied to any similarity, and used like one
+	_, _, err := GetRecommendationsWithOptions(MakeRatingMatrix(append(many[0], few[0]...), 2, 4), 1, nil,
+		Options{Similarity: Shrinkage(Pearson, 10)})
+	Assert(t, err == nil)
+}
+
+func TestSetJaccard(t *testing.T) {
+	Assert(t, SetJaccard([]int{1, 5, 9}, []int{9, 5, 7, 5}) == 0.5)
+	Assert(t, SetJaccard(nil, nil) == 0)
+	Assert(t, SetJaccard([]int{1}, []int{2}) == 0)
+}
+
+func TestWeightedJaccard(t *testing.T) {
+	Assert(t, WeightedJaccard([]float64{3, 0, 1}, []float64{1, 2, 1}) == 2.0/6)
+	x := []float64{1, 1, 0, 1, 1}
+	y := []float64{1, 1, 1, 0, 1}
+	Assert(t, WeightedJaccard(x, y) == Jaccard(x, y))
+}
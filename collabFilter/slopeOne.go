@@ -0,0 +1,177 @@
+package collabFilter
+
+import (
+	"errors"
+
+	. "github.com/skelterjohn/go.matrix"
+)
+
+// deviation tables: for every pair of products (i, j), the sum of r_i - r_j and the number of users
+// that rated both.
+type deviations struct {
+	sums, counts *DenseMatrix
+}
+
+func newDeviations(n_items int) deviations {
+	return deviations{Zeros(n_items, n_items), Zeros(n_items, n_items)}
+}
+
+// adds (sign 1) or removes (sign -1) the pairs of the given ratings (product -> rating).
+func (d deviations) add(ratings map[int]float64, sign float64) {
+	for i, r_i := range ratings {
+		for j, r_j := range ratings {
+			if i != j {
+				d.sums.Set(i, j, d.sums.Get(i, j)+sign*(r_i-r_j))
+				d.counts.Set(i, j, d.counts.Get(i, j)+sign)
+			}
+		}
+	}
+}
+
+// Weighted Slope One, from Lemire and Maclachlan "Slope One Predictors for Online Rating-Based
+// Collaborative Filtering" (SDM 2005). A user's rating of product j is predicted from each product i they
+// rated as r_i + dev(j, i), the average difference between the ratings of j and i, weighted by the number
+// of users who rated both.
+// With BiPolar, the products a user liked (rated above their mean) and disliked (below their mean) are kept
+// apart: deviations are only learned from users who liked, or disliked, both products, and only applied to
+// products the user liked, or disliked, in turn.
+// The tables are kept up to date as ratings arrive with Update.
+type SlopeOne struct {
+	BiPolar bool
+
+	prefs *DenseMatrix
+	// deviations over all users, and for Bi-Polar Slope One, over the liked and disliked products.
+	all, liked, disliked deviations
+}
+
+// Params: the prefs matrix (0 or NaN entries are missing) and whether to use Bi-Polar Slope One.
+// Returns the model with the deviation tables of every product pair. The model keeps its own copy of prefs,
+// so Update doesn't change the caller's matrix (or the other models built from it).
+func NewSlopeOne(prefs *DenseMatrix, biPolar bool) *SlopeOne {
+	prefs = replaceNA(prefs)
+	n_items := prefs.Cols()
+	model := &SlopeOne{BiPolar: biPolar, prefs: prefs, all: newDeviations(n_items)}
+	if biPolar {
+		model.liked, model.disliked = newDeviations(n_items), newDeviations(n_items)
+	}
+	for u := 0; u < prefs.Rows(); u++ {
+		model.add(u, 1)
+	}
+	return model
+}
+
+// the products a user rated (product -> rating), split into the ones rated above and below the user's mean.
+func (m *SlopeOne) ratings(user int) (all, liked, disliked map[int]float64) {
+	all, liked, disliked = make(map[int]float64), make(map[int]float64), make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if rating := m.prefs.Get(user, i); rating != 0 {
+			all[i] = rating
+		}
+	}
+	if !m.BiPolar || len(all) == 0 {
+		return
+	}
+	mean := float64(0)
+	for _, rating := range all {
+		mean += rating
+	}
+	mean /= float64(len(all))
+	for i, rating := range all {
+		if rating > mean {
+			liked[i] = rating
+		} else if rating < mean {
+			disliked[i] = rating
+		}
+	}
+	return
+}
+
+// adds (sign 1) or removes (sign -1) the contribution of a user to the tables.
+func (m *SlopeOne) add(user int, sign float64) {
+	all, liked, disliked := m.ratings(user)
+	m.all.add(all, sign)
+	if m.BiPolar {
+		m.liked.add(liked, sign)
+		m.disliked.add(disliked, sign)
+	}
+}
+
+// Returns the average deviation of product i from product j (how much higher i is rated), and the
+// number of users who rated both.
+func (m *SlopeOne) Deviation(i, j int) (float64, int) {
+	count := m.all.counts.Get(i, j)
+	if count == 0 {
+		return 0, 0
+	}
+	return m.all.sums.Get(i, j) / count, int(count)
+}
+
+// Sets a user's rating of a product (0 removes it), and updates the tables. Only the pairs of products
+// the user rated change, so an update costs O(n^2) in the number of ratings of the user. Error if out of range.
+func (m *SlopeOne) Update(user, item int, rating float64) error {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return errors.New("user/product index out of range")
+	}
+	// with Bi-Polar Slope One, a new rating can move the user's mean and so which products are liked
+	m.add(user, -1)
+	m.prefs.Set(user, item, rating)
+	m.add(user, 1)
+	return nil
+}
+
+func (m *SlopeOne) predict(user, item int) (pred float64, ok bool) {
+	all, liked, disliked := m.ratings(user)
+	num, den := float64(0), float64(0)
+	apply := func(ratings map[int]float64, table deviations) {
+		for i, rating := range ratings {
+			if i == item {
+				continue
+			}
+			if count := table.counts.Get(item, i); count > 0 {
+				num += table.sums.Get(item, i) + rating*count
+				den += count
+			}
+		}
+	}
+	if m.BiPolar {
+		apply(liked, m.liked)
+		apply(disliked, m.disliked)
+	} else {
+		apply(all, m.all)
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// Returns the predicted rating of a user for a product. Error if out of range, or if no product the user
+// rated has a deviation with it.
+func (m *SlopeOne) Predict(user, item int) (float64, error) {
+	if user >= m.prefs.Rows() || item >= m.prefs.Cols() || user < 0 || item < 0 {
+		return 0, errors.New("user/product index out of range")
+	}
+	pred, ok := m.predict(user, item)
+	if !ok {
+		return 0, errors.New("no deviations to predict from")
+	}
+	return pred, nil
+}
+
+// Gets Recommendations for a user (row index) for the products they haven't rated.
+// Returns products and predicted ratings in descending order.
+func (m *SlopeOne) GetRecommendations(user int, products []string) ([]string, []float64, error) {
+	if user >= m.prefs.Rows() || user < 0 {
+		return nil, nil, errors.New("user index out of range")
+	}
+	scores := make(map[int]float64)
+	for i := 0; i < m.prefs.Cols(); i++ {
+		if m.prefs.Get(user, i) == 0 {
+			if pred, ok := m.predict(user, i); ok {
+				scores[i] = pred
+			}
+		}
+	}
+	prods, vals := rank(scores, products)
+	return prods, vals, nil
+}
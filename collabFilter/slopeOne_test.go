@@ -0,0 +1,85 @@
+package collabFilter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSlopeOne(t *testing.T) {
+	// the example of Lemire and Maclachlan: John, Mark and Lucy rating products A, B, C
+	prefs := MakeRatingMatrix([]float64{
+		5, 3, 2,
+		3, 4, 0,
+		0, 2, 5}, 3, 3)
+	model := NewSlopeOne(prefs, false)
+	dev, count := model.Deviation(0, 1)
+	Assert(t, dev == 0.5 && count == 2, dev, count)
+	dev, count = model.Deviation(1, 0)
+	Assert(t, dev == -0.5 && count == 2, dev, count)
+	// ((2 + 0.5) * 2 + (5 + 3) * 1) / 3
+	pred, err := model.Predict(2, 0)
+	Assert(t, err == nil && math.Abs(pred-13.0/3) < 1e-9, pred)
+
+	prods, scores, err := model.GetRecommendations(2, []string{"A", "B", "C"})
+	Assert(t, err == nil && len(prods) == 1 && prods[0] == "A" && scores[0] == pred, prods, scores)
+	_, err = model.Predict(3, 0)
+	Assert(t, err != nil)
+}
+
+func TestBiPolarSlopeOne(t *testing.T) {
+	prefs := MakeRatingMatrix([]float64{
+		5, 4, 1,
+		4, 2, 1,
+		0, 5, 1}, 3, 3)
+	// only user 0 likes both A and B, and nobody dislikes both A and C
+	pred, err := NewSlopeOne(prefs.Copy(), true).Predict(2, 0)
+	Assert(t, err == nil && pred == 6, pred)
+	// ((5 + 1.5) * 2 + (1 + 3.5) * 2) / 4
+	pred, err = NewSlopeOne(prefs.Copy(), false).Predict(2, 0)
+	Assert(t, err == nil && pred == 5.5, pred)
+}
+
+func TestSlopeOneUpdate(t *testing.T) {
+	for _, biPolar := range []bool{false, true} {
+		model := NewSlopeOne(testPrefs(), biPolar)
+		Assert(t, model.Update(1, 1, 5) == nil)
+		Assert(t, model.Update(0, 2, 0) == nil)
+		Assert(t, model.Update(3, 2, 2) == nil)
+		Assert(t, model.Update(5, 0, 1) != nil)
+		// same tables as a model built from the new ratings
+		rebuilt := NewSlopeOne(model.prefs.Copy(), biPolar)
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 5; j++ {
+				dev, count := model.Deviation(i, j)
+				rebuilt_dev, rebuilt_count := rebuilt.Deviation(i, j)
+				Assert(t, math.Abs(dev-rebuilt_dev) < 1e-9 && count == rebuilt_count, i, j)
+				if biPolar {
+					Assert(t, model.liked.counts.Get(i, j) == rebuilt.liked.counts.Get(i, j))
+					Assert(t, model.disliked.counts.Get(i, j) == rebuilt.disliked.counts.Get(i, j))
+				}
+			}
+		}
+		for u := 0; u < 5; u++ {
+			a, _, err := model.GetRecommendations(u, nil)
+			Assert(t, err == nil)
+			b, _, _ := rebuilt.GetRecommendations(u, nil)
+			Assert(t, len(a) == len(b), u)
+		}
+	}
+}
+
+func TestSlopeOneSharedPrefs(t *testing.T) {
+	// models built from the same matrix don't see each other's updates
+	prefs := testPrefs()
+	model := NewSlopeOne(prefs, false)
+	similarities := NewSimilarityModel(prefs, false, 2, CosineSim)
+	Assert(t, similarities.Update(0, 1, 1) == nil)
+	Assert(t, model.Update(2, 3, 5) == nil)
+
+	updated := testPrefs()
+	updated.Set(2, 3, 5)
+	dev, count := model.Deviation(0, 1)
+	rebuilt_dev, rebuilt_count := NewSlopeOne(updated, false).Deviation(0, 1)
+	Assert(t, math.Abs(dev-rebuilt_dev) < 1e-9 && count == rebuilt_count, dev, rebuilt_dev)
+	Assert(t, prefs.Get(0, 1) == testPrefs().Get(0, 1) && prefs.Get(2, 3) == testPrefs().Get(2, 3), prefs)
+}
@@ -1,6 +1,7 @@
 package collabFilter
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strconv"
@@ -31,41 +32,57 @@ func min(vals []int) int {
 
 // read file with separator and load into a matrix.
 // If user/product ID's start at 1, set first product/user at row/col index 0.
-// Already tested in ALS package
-func Load(path, sep string) *DenseMatrix {
+// Error if the file can't be read, or if a line isn't a user, product and rating.
+func Load(path, sep string) (*DenseMatrix, error) {
 	// read in the file
 	f, err := ioutil.ReadFile(path)
-	errcheck(err)
+	if err != nil {
+		return nil, err
+	}
 	lines := strings.Split(string(f), "\n")
 
-	// determine the number of rows and columns
-	col_count := make([]int, 0)
-	row_count := make([]int, 0)
-	for _, line := range lines {
-		values := strings.Split(line, sep)
-		if line != "" {
-			row, _ := strconv.Atoi(values[0])
-			col, _ := strconv.Atoi(values[1])
-			row_count = append(row_count, row)
-			col_count = append(col_count, col)
+	rows := make([]int, 0)
+	cols := make([]int, 0)
+	vals := make([]float64, 0)
+	for idx, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
 		}
-	}
-	// initialize all values to 0
-	mat := Zeros(max(row_count), max(col_count))
-	// and set the values accordingly
-	for _, line := range lines {
-		values := strings.Split(line, sep)
-		fmt.Println(values)
-		if line != "" {
-			row, _ := strconv.Atoi(values[0])
-			col, _ := strconv.Atoi(values[1])
-			val, _ := strconv.ParseFloat(values[2], 64)
-			if min(col_count) == 1 {
-				mat.Set(row-1, col-1, val)
-			} else {
-				mat.Set(row, col, val)
-			}
+		row, col, val, err := parseLine(strings.Split(line, sep))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, idx+1, err)
 		}
+		rows = append(rows, row)
+		cols = append(cols, col)
+		vals = append(vals, val)
+	}
+	offset := 0
+	if len(cols) > 0 && min(cols) == 1 {
+		offset = 1
+	}
+	if len(rows) > 0 && (min(rows) < offset || min(cols) < offset) {
+		return nil, errors.New("user/product ID's can't be negative")
+	}
+	// initialize all values to 0, and set the values accordingly
+	mat := Zeros(max(rows)+1-offset, max(cols)+1-offset)
+	for i := range vals {
+		mat.Set(rows[i]-offset, cols[i]-offset, vals[i])
+	}
+	logger.Debug("ratings loaded", "path", path, "rows", mat.Rows(), "cols", mat.Cols())
+	return mat, nil
+}
+
+// the user, product and rating of a line
+func parseLine(values []string) (row, col int, val float64, err error) {
+	if len(values) < 3 {
+		return 0, 0, 0, errors.New("need a user, product and rating")
+	}
+	if row, err = strconv.Atoi(strings.TrimSpace(values[0])); err != nil {
+		return
+	}
+	if col, err = strconv.Atoi(strings.TrimSpace(values[1])); err != nil {
+		return
 	}
-	return mat
+	val, err = strconv.ParseFloat(strings.TrimSpace(values[2]), 64)
+	return
 }
@@ -0,0 +1,258 @@
+// Second-order Factorization Machines (Rendle, 2010) in Go
+package factorizationMachine
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// a single non-zero entry of a sparse feature vector
+type Feature struct {
+	Index int
+	Value float64
+}
+
+// a sparse feature vector (user one-hot, item one-hot, context, metadata...) and its target.
+type Row struct {
+	Target   float64
+	Features []Feature
+}
+
+// a positive and a negative feature vector for the BPR ranking objective, e.g. the same user
+// with an item they interacted with and one they did not.
+type Pair struct {
+	Positive, Negative []Feature
+}
+
+// Factorization Machine model:
+// y(x) = W0 + sum_l W[l] * x_l + sum_l<j <V[l], V[j]> * x_l * x_j
+type FM struct {
+	W0 float64
+	W  []float64
+	// factors of each feature (features x factors)
+	V [][]float64
+}
+
+// returns the number of features referenced, i.e. the largest feature index + 1.
+// Error if an index is negative.
+func numFeatures(rows [][]Feature) (int, error) {
+	n := 0
+	for _, row := range rows {
+		for _, feature := range row {
+			if feature.Index < 0 {
+				return 0, errors.New("Feature indices can't be negative")
+			}
+			if feature.Index+1 > n {
+				n = feature.Index + 1
+			}
+		}
+	}
+	return n, nil
+}
+
+// creates a model with zero weights and small random factors.
+func newFM(n_features, n_factors int, seed int64) *FM {
+	r := rand.New(rand.NewSource(seed))
+	model := &FM{W: make([]float64, n_features), V: make([][]float64, n_features)}
+	for l := 0; l < n_features; l++ {
+		model.V[l] = make([]float64, n_factors)
+		for f := 0; f < n_factors; f++ {
+			model.V[l][f] = 0.1 * r.NormFloat64()
+		}
+	}
+	return model
+}
+
+// returns q_f = sum_l V[l][f] * x_l for every factor. Features the model doesn't know are skipped.
+func (m *FM) sums(x []Feature) []float64 {
+	if len(m.V) == 0 {
+		return nil
+	}
+	q := make([]float64, len(m.V[0]))
+	for _, feature := range x {
+		if feature.Index >= 0 && feature.Index < len(m.V) {
+			for f := 0; f < len(q); f++ {
+				q[f] += m.V[feature.Index][f] * feature.Value
+			}
+		}
+	}
+	return q
+}
+
+// Returns the prediction for a feature vector. Features that weren't seen in training are ignored.
+// Computed in O(k*n) using sum_l<j <v_l, v_j> x_l x_j = 1/2 sum_f [(sum_l v_lf x_l)^2 - sum_l v_lf^2 x_l^2].
+func (m *FM) Predict(x []Feature) float64 {
+	pred := m.W0
+	q := m.sums(x)
+	for _, feature := range x {
+		if feature.Index >= 0 && feature.Index < len(m.W) {
+			pred += m.W[feature.Index] * feature.Value
+			for f := 0; f < len(q); f++ {
+				v := m.V[feature.Index][f] * feature.Value
+				pred -= 0.5 * v * v
+			}
+		}
+	}
+	for f := 0; f < len(q); f++ {
+		pred += 0.5 * q[f] * q[f]
+	}
+	return pred
+}
+
+// moves every parameter involved in x along scale * dy(x)/dtheta, with L2 regularization.
+func (m *FM) step(x []Feature, scale, lambda, learning_rate float64) {
+	q := m.sums(x)
+	for _, feature := range x {
+		l, x_l := feature.Index, feature.Value
+		m.W[l] += learning_rate * (scale*x_l - lambda*m.W[l])
+		for f := 0; f < len(q); f++ {
+			grad := x_l*q[f] - m.V[l][f]*x_l*x_l
+			m.V[l][f] += learning_rate * (scale*grad - lambda*m.V[l][f])
+		}
+	}
+}
+
+// Params: the rows, number of factors, iterations (epochs), lambda and learning rate.
+// Trains a regression model (squared loss) with stochastic gradient descent.
+func TrainSGD(rows []Row, n_factors, iterations int, lambda, learning_rate float64) (*FM, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("Need at least one row to train on")
+	}
+	features := make([][]Feature, len(rows))
+	for idx, row := range rows {
+		features[idx] = row.Features
+	}
+	n_features, err := numFeatures(features)
+	if err != nil {
+		return nil, err
+	}
+	model := newFM(n_features, n_factors, 47)
+	for ii := 0; ii < iterations; ii++ {
+		for _, row := range rows {
+			e := row.Target - model.Predict(row.Features)
+			model.W0 += learning_rate * e
+			model.step(row.Features, e, lambda, learning_rate)
+		}
+		logger.Debug("FM SGD epoch", "epoch", ii+1)
+	}
+	return model, nil
+}
+
+// Params: the positive/negative pairs, number of factors, iterations (epochs), lambda and learning rate.
+// Trains a ranking model with stochastic gradient descent on the BPR loss, -ln sigmoid(y(x+) - y(x-)).
+// The global bias cancels out in this objective and stays at 0.
+func TrainBPR(pairs []Pair, n_factors, iterations int, lambda, learning_rate float64) (*FM, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("Need at least one pair to train on")
+	}
+	features := make([][]Feature, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		features = append(features, pair.Positive, pair.Negative)
+	}
+	n_features, err := numFeatures(features)
+	if err != nil {
+		return nil, err
+	}
+	model := newFM(n_features, n_factors, 47)
+	for ii := 0; ii < iterations; ii++ {
+		for _, pair := range pairs {
+			diff := model.Predict(pair.Positive) - model.Predict(pair.Negative)
+			// derivative of ln sigmoid(diff)
+			g := 1 / (1 + math.Exp(diff))
+			model.step(pair.Positive, g, lambda, learning_rate)
+			model.step(pair.Negative, -g, lambda, learning_rate)
+		}
+		logger.Debug("FM BPR epoch", "epoch", ii+1)
+	}
+	return model, nil
+}
+
+// Params: the rows, number of factors, iterations and lambda.
+// Trains a regression model with alternating least squares (Rendle et al., 2011): each parameter is
+// set to its least squares solution with the others fixed, so there is no learning rate to tune.
+// Returns the model and the final squared error.
+func TrainALS(rows []Row, n_factors, iterations int, lambda float64) (*FM, float64, error) {
+	if len(rows) == 0 {
+		return nil, 0, errors.New("Need at least one row to train on")
+	}
+	features := make([][]Feature, len(rows))
+	for idx, row := range rows {
+		features[idx] = row.Features
+	}
+	n_features, err := numFeatures(features)
+	if err != nil {
+		return nil, 0, err
+	}
+	model := newFM(n_features, n_factors, 47)
+
+	// rows (and the value of the feature) in which each feature appears
+	type occurrence struct {
+		row   int
+		value float64
+	}
+	occurrences := make([][]occurrence, n_features)
+	for idx, row := range rows {
+		for _, feature := range row.Features {
+			occurrences[feature.Index] = append(occurrences[feature.Index], occurrence{idx, feature.Value})
+		}
+	}
+	// residuals y - y(x) and factor sums q for each row, kept in sync with every update
+	residuals := make([]float64, len(rows))
+	q := make([][]float64, len(rows))
+	for idx, row := range rows {
+		residuals[idx] = row.Target - model.Predict(row.Features)
+		q[idx] = model.sums(row.Features)
+	}
+
+	error_value := float64(0)
+	for ii := 0; ii < iterations; ii++ {
+		// global bias, h(x) = 1
+		old := model.W0
+		model.W0 = 0
+		for idx := range rows {
+			model.W0 += residuals[idx] + old
+		}
+		model.W0 /= float64(len(rows)) + lambda
+		for idx := range rows {
+			residuals[idx] -= model.W0 - old
+		}
+		// linear weights, h(x) = x_l
+		for l := 0; l < n_features; l++ {
+			old := model.W[l]
+			numer, denom := float64(0), lambda
+			for _, o := range occurrences[l] {
+				numer += (residuals[o.row] + old*o.value) * o.value
+				denom += o.value * o.value
+			}
+			model.W[l] = numer / denom
+			for _, o := range occurrences[l] {
+				residuals[o.row] -= (model.W[l] - old) * o.value
+			}
+		}
+		// factors, h(x) = x_l * (q_f - v_lf * x_l)
+		for f := 0; f < n_factors; f++ {
+			for l := 0; l < n_features; l++ {
+				old := model.V[l][f]
+				numer, denom := float64(0), lambda
+				h := make([]float64, len(occurrences[l]))
+				for idx, o := range occurrences[l] {
+					h[idx] = o.value * (q[o.row][f] - old*o.value)
+					numer += (residuals[o.row] + old*h[idx]) * h[idx]
+					denom += h[idx] * h[idx]
+				}
+				model.V[l][f] = numer / denom
+				for idx, o := range occurrences[l] {
+					residuals[o.row] -= (model.V[l][f] - old) * h[idx]
+					q[o.row][f] += (model.V[l][f] - old) * o.value
+				}
+			}
+		}
+		error_value = 0
+		for idx := range rows {
+			error_value += residuals[idx] * residuals[idx]
+		}
+		logger.Debug("FM ALS iteration", "iteration", ii+1, "error", error_value)
+	}
+	return model, error_value, nil
+}
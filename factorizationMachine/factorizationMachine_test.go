@@ -0,0 +1,76 @@
+package factorizationMachine
+
+import (
+	"testing"
+)
+
+func Assert(t *testing.T, condition bool, args ...interface{}) {
+	if !condition {
+		t.Fatal(args...)
+	}
+}
+
+// users 0-2 are features 0-2, items 0-3 are features 3-6, and feature 7 is a "weekend" context.
+func ratings() []Row {
+	row := func(target float64, user, item int, weekend bool) Row {
+		features := []Feature{{user, 1}, {3 + item, 1}}
+		if weekend {
+			features = append(features, Feature{7, 1})
+		}
+		return Row{target, features}
+	}
+	return []Row{row(5, 0, 0, false), row(4, 0, 1, false), row(1, 0, 2, true),
+		row(4, 1, 0, false), row(2, 1, 2, true), row(1, 1, 3, true),
+		row(1, 2, 0, false), row(5, 2, 2, true), row(4, 2, 3, true)}
+}
+
+func TestLoad(t *testing.T) {
+	rows, err := Load("../testdata/libsvm.txt")
+	Assert(t, err == nil && len(rows) == 5, rows)
+	Assert(t, rows[0].Target == 5 && len(rows[0].Features) == 3)
+	Assert(t, rows[4].Features[2].Index == 7 && rows[4].Features[2].Value == 0.5)
+	_, err = Load("../testdata/missing.txt")
+	Assert(t, err != nil)
+}
+
+func TestPredict(t *testing.T) {
+	model := &FM{W0: 1, W: []float64{1, 2, 3}, V: [][]float64{{1, 0}, {1, 1}, {0, 2}}}
+	// 1 + 1 + 2*2 + <v0, v1>*2 = 6 + 2
+	pred := model.Predict([]Feature{{0, 1}, {1, 2}})
+	Assert(t, pred == 8, pred)
+	// unknown features are ignored
+	Assert(t, model.Predict([]Feature{{0, 1}, {1, 2}, {10, 1}}) == pred)
+}
+
+func TestTrainSGD(t *testing.T) {
+	rows := ratings()
+	model, err := TrainSGD(rows, 2, 200, 0.01, 0.05)
+	Assert(t, err == nil)
+	for _, row := range rows {
+		pred := model.Predict(row.Features)
+		Assert(t, pred > row.Target-1 && pred < row.Target+1, row, pred)
+	}
+	_, err = TrainSGD([]Row{{1, []Feature{{-1, 1}}}}, 2, 1, 0.01, 0.05)
+	Assert(t, err != nil)
+}
+
+func TestTrainALS(t *testing.T) {
+	rows := ratings()
+	model, err_value, err := TrainALS(rows, 2, 20, 0.01)
+	Assert(t, err == nil)
+	Assert(t, err_value < 1, err_value)
+	Assert(t, model.Predict(rows[0].Features) > model.Predict(rows[2].Features))
+}
+
+func TestTrainBPR(t *testing.T) {
+	// user 0 prefers items 0, 1 over 2, 3; user 1 the other way round.
+	pair := func(user, pos, neg int) Pair {
+		return Pair{[]Feature{{user, 1}, {2 + pos, 1}}, []Feature{{user, 1}, {2 + neg, 1}}}
+	}
+	pairs := []Pair{pair(0, 0, 2), pair(0, 1, 3), pair(0, 0, 3), pair(1, 2, 0), pair(1, 3, 1), pair(1, 2, 1)}
+	model, err := TrainBPR(pairs, 2, 200, 0.01, 0.1)
+	Assert(t, err == nil)
+	// held out comparisons
+	Assert(t, model.Predict([]Feature{{0, 1}, {3, 1}}) > model.Predict([]Feature{{0, 1}, {4, 1}}))
+	Assert(t, model.Predict([]Feature{{1, 1}, {5, 1}}) > model.Predict([]Feature{{1, 1}, {3, 1}}))
+}
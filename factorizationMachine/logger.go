@@ -0,0 +1,26 @@
+package factorizationMachine
+
+// Receives the log output of the package: errors, and debug output such as training progress. Args are
+// key-value pairs, so a *slog.Logger can be used directly. Nothing is logged by default.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+var logger Logger = nopLogger{}
+
+// Sets the logger of the package, e.g. SetLogger(slog.Default()). nil silences it again.
+// Not safe to call while models are being trained.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
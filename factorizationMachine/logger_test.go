@@ -0,0 +1,21 @@
+package factorizationMachine
+
+import (
+	"testing"
+)
+
+// keeps the messages logged at each level
+type recordingLogger map[string][]string
+
+func (r recordingLogger) Debug(msg string, args ...interface{}) { r["debug"] = append(r["debug"], msg) }
+func (r recordingLogger) Info(msg string, args ...interface{})  { r["info"] = append(r["info"], msg) }
+func (r recordingLogger) Error(msg string, args ...interface{}) { r["error"] = append(r["error"], msg) }
+
+func TestLogger(t *testing.T) {
+	logs := recordingLogger{}
+	SetLogger(logs)
+	defer SetLogger(nil)
+	rows := []Row{{5, []Feature{{0, 1}, {2, 1}}}, {1, []Feature{{1, 1}, {2, 1}}}}
+	_, _, err := TrainALS(rows, 2, 4, 0.1)
+	Assert(t, err == nil && len(logs["debug"]) == 4, logs)
+}
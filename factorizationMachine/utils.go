@@ -0,0 +1,45 @@
+package factorizationMachine
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// read a file in libsvm format and load it into rows. Each line is a target followed by
+// index:value pairs, e.g. "4 0:1 12:1 40:0.5". Malformed pairs are skipped.
+// Error if the file can't be read.
+func Load(path string) ([]Row, error) {
+	f, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(f), "\n")
+
+	rows := make([]Row, 0)
+	for _, line := range lines {
+		values := strings.Fields(line)
+		if len(values) == 0 {
+			continue
+		}
+		target, _ := strconv.ParseFloat(values[0], 64)
+		row := Row{Target: target}
+		for _, pair := range values[1:] {
+			idx_val := strings.Split(pair, ":")
+			if len(idx_val) != 2 {
+				continue
+			}
+			idx, err := strconv.Atoi(idx_val[0])
+			if err != nil {
+				continue
+			}
+			val, err := strconv.ParseFloat(idx_val[1], 64)
+			if err != nil {
+				continue
+			}
+			row.Features = append(row.Features, Feature{idx, val})
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}